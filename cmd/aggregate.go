@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// otherOrgs labels the rollup row of PRs outside the configured orgs.
+const otherOrgs = "(other)"
+
+// aggregateModes are the accepted --aggregate values.
+var aggregateModes = []string{"org"}
+
+func validateAggregate() error {
+	if aggregate == "" {
+		return nil
+	}
+	if aggregate != "org" {
+		return fmt.Errorf("unknown --aggregate mode %q, expected one of: %s", aggregate, strings.Join(aggregateModes, ", "))
+	}
+	if countOnly || useGraphQL {
+		return fmt.Errorf("--aggregate groups the fetched PRs and can't be combined with --count-only or --use-graphql")
+	}
+	if limit > 0 {
+		return fmt.Errorf("--aggregate needs every PR and can't be combined with --limit")
+	}
+	if breakdown != "" {
+		return fmt.Errorf("--aggregate can't be combined with --breakdown")
+	}
+	if outputFormat != "table" && outputFormat != "markdown" {
+		return fmt.Errorf("--aggregate only supports --output table and markdown")
+	}
+	return nil
+}
+
+// orgRollup is a row of the --aggregate org table.
+type orgRollup struct {
+	Org    string
+	Counts map[string]int
+	Total  int
+}
+
+// configuredOrgs returns the orgs config searches, from the orgs, the owners
+// of the repos, the scopes and the per-handle overrides.
+func configuredOrgs(config pullpanda.Config) map[string]bool {
+	orgs := make(map[string]bool)
+	addRepos := func(repos []string) {
+		for _, repo := range repos {
+			if owner, _, ok := strings.Cut(repo, "/"); ok {
+				orgs[strings.ToLower(owner)] = true
+			}
+		}
+	}
+	for _, org := range config.Orgs {
+		orgs[strings.ToLower(org)] = true
+	}
+	addRepos(config.Repos)
+	for _, scope := range config.Scopes {
+		orgs[strings.ToLower(scope.Org)] = true
+	}
+	for _, h := range config.Handles {
+		for _, org := range h.Orgs {
+			orgs[strings.ToLower(org)] = true
+		}
+		addRepos(h.Repos)
+	}
+	return orgs
+}
+
+// prStatus returns which of statuses pr was counted under: merged PRs under
+// merged, or closed when merged isn't queried, like the search does.
+func prStatus(pr pullpanda.PullRequest, statuses []string) string {
+	if pr.State == "open" {
+		return "open"
+	}
+	if pr.Merged {
+		for _, status := range statuses {
+			if status == "merged" {
+				return "merged"
+			}
+		}
+	}
+	return "closed"
+}
+
+// rollUpOrgs groups the PRs of summaries by the org owning their repository,
+// largest total first and by name among equal totals. With orgs configured,
+// PRs of any other org, e.g. found by an unscoped handle, are grouped in a
+// final otherOrgs row.
+func rollUpOrgs(summaries []pullpanda.Summary, statuses []string, configured map[string]bool) []orgRollup {
+	byOrg := make(map[string]*orgRollup)
+	for _, summary := range summaries {
+		for _, pr := range summary.PRs {
+			org, _, _ := strings.Cut(pr.Repository, "/")
+			org = strings.ToLower(org)
+			if org == "" || (len(configured) > 0 && !configured[org]) {
+				org = otherOrgs
+			}
+			rollup := byOrg[org]
+			if rollup == nil {
+				rollup = &orgRollup{Org: org, Counts: make(map[string]int)}
+				byOrg[org] = rollup
+			}
+			rollup.Counts[prStatus(pr, statuses)]++
+			rollup.Total++
+		}
+	}
+
+	rollups := make([]orgRollup, 0, len(byOrg))
+	for _, rollup := range byOrg {
+		rollups = append(rollups, *rollup)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i], rollups[j]
+		if (a.Org == otherOrgs) != (b.Org == otherOrgs) {
+			return b.Org == otherOrgs
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Org < b.Org
+	})
+	return rollups
+}
+
+// orgTable builds the header, rows and totals footer of the org rollup.
+func orgTable(rollups []orgRollup, statuses []string) ([]string, [][]string, []string) {
+	header := append(append([]string{"Org"}, statuses...), "Total")
+	totals := make(map[string]int)
+	grand := 0
+	var rows [][]string
+	for _, rollup := range rollups {
+		row := []string{rollup.Org}
+		for _, status := range statuses {
+			row = append(row, formatCount(rollup.Counts[status]))
+			totals[status] += rollup.Counts[status]
+		}
+		rows = append(rows, append(row, formatCount(rollup.Total)))
+		grand += rollup.Total
+	}
+	footer := []string{"Total"}
+	for _, status := range statuses {
+		footer = append(footer, formatCount(totals[status]))
+	}
+	return header, rows, append(footer, formatCount(grand))
+}
+
+// renderOrgReport writes the --aggregate org table in the --output format.
+func renderOrgReport(w io.Writer, result pullpanda.RunResult, config pullpanda.Config) {
+	header, rows, footer := orgTable(rollUpOrgs(shownSummaries(result.Summaries), config.Statuses, configuredOrgs(config)), config.Statuses)
+	if outputFormat == "markdown" {
+		writeMarkdownRow(w, header)
+		separator := []string{"---"}
+		for range header[1:] {
+			separator = append(separator, "---:")
+		}
+		writeMarkdownRow(w, separator)
+		for _, row := range rows {
+			writeMarkdownRow(w, row)
+		}
+		if !noFooter {
+			cells := markdownCells(footer)
+			cells[0] = "**" + cells[0] + "**"
+			fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		}
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.AppendBulk(rows)
+	if !noFooter {
+		table.SetFooter(footer)
+		table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
+	}
+	table.Render()
+}
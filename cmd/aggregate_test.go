@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestRollUpOrgs(t *testing.T) {
+	merged := pullpanda.PullRequest{State: "closed", Merged: true}
+	closed := pullpanda.PullRequest{State: "closed"}
+	open := pullpanda.PullRequest{State: "open"}
+	in := func(pr pullpanda.PullRequest, repo string) pullpanda.PullRequest {
+		pr.Repository = repo
+		return pr
+	}
+	summaries := []pullpanda.Summary{
+		{Handle: "octocat", PRs: []pullpanda.PullRequest{in(merged, "Octo/api"), in(merged, "octo/web"), in(open, "acme/site"), in(merged, "stray/tool")}},
+		{Handle: "hubot", PRs: []pullpanda.PullRequest{in(closed, "octo/api"), in(merged, "acme/site"), in(open, "zeta/lib")}},
+	}
+	statuses := []string{"merged", "open", "closed"}
+	config := pullpanda.Config{
+		Orgs:    []string{"octo"},
+		Handles: []pullpanda.Handle{{Handle: "hubot", Repos: []string{"Acme/site"}}},
+	}
+
+	tests := []struct {
+		name       string
+		configured map[string]bool
+		want       []orgRollup
+	}{
+		{
+			name:       "configured orgs",
+			configured: configuredOrgs(config),
+			want: []orgRollup{
+				{Org: "octo", Counts: map[string]int{"merged": 2, "closed": 1}, Total: 3},
+				{Org: "acme", Counts: map[string]int{"merged": 1, "open": 1}, Total: 2},
+				{Org: otherOrgs, Counts: map[string]int{"merged": 1, "open": 1}, Total: 2},
+			},
+		},
+		{
+			name: "no orgs configured",
+			want: []orgRollup{
+				{Org: "octo", Counts: map[string]int{"merged": 2, "closed": 1}, Total: 3},
+				{Org: "acme", Counts: map[string]int{"merged": 1, "open": 1}, Total: 2},
+				{Org: "stray", Counts: map[string]int{"merged": 1}, Total: 1},
+				{Org: "zeta", Counts: map[string]int{"open": 1}, Total: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := rollUpOrgs(summaries, statuses, tt.configured)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: rollUpOrgs = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// Without merged queried, merged PRs are counted as closed like the
+	// search counts them
+	got := rollUpOrgs(summaries[:1], []string{"closed"}, map[string]bool{"octo": true})
+	if got[0].Org != "octo" || got[0].Counts["closed"] != 2 {
+		t.Errorf("closed-only rollup = %+v, want octo with 2 closed", got)
+	}
+}
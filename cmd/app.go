@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// appAuth is the GitHub App installation auth built from the --app-* flags,
+// nil when the token is used.
+var appAuth *pullpanda.AppAuth
+
+// usesApp reports whether any of the GitHub App flags is set.
+func usesApp() bool {
+	return appID != 0 || appPrivateKey != "" || installationID != 0
+}
+
+// loadAppAuth checks that the GitHub App flags are set together and reads
+// the private key.
+func loadAppAuth() (*pullpanda.AppAuth, error) {
+	if appID == 0 || appPrivateKey == "" || installationID == 0 {
+		return nil, errors.New("--app-id, --app-private-key and --installation-id must be set together")
+	}
+	data, err := ioutil.ReadFile(appPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error reading app private key: %w", err)
+	}
+	key, err := pullpanda.ParseAppPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &pullpanda.AppAuth{AppID: appID, InstallationID: installationID, PrivateKey: key}, nil
+}
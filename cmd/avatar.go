@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"context"
+	"log"
+)
+
+// avatarPlaceholder is shown when a handle's avatar can't be fetched.
+const avatarPlaceholder = "data:image/svg+xml,%3Csvg xmlns='http://www.w3.org/2000/svg' width='20' height='20'%3E%3Crect width='20' height='20' fill='%23d0d7de'/%3E%3C/svg%3E"
+
+// avatarURL returns the avatar of a GitHub handle, looked up once per run.
+// Failed lookups resolve to the placeholder.
+func avatarURL(handle string) string {
+	avatar, err := apiClient.AvatarURL(context.Background(), handle)
+	if err != nil {
+		if enableLog {
+			log.Printf("Could not fetch avatar for %s: %v\n", handle, err)
+		}
+		return avatarPlaceholder
+	}
+	return avatar
+}
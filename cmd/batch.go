@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch CONFIG...",
+	Short: "Report on several configs in one run",
+	Long: `Batch renders the report of every config file in turn, each under a heading
+with its file name, over the same date range and flags. One client serves
+all of them, so connections, cached lookups such as team members, and the
+rate-limit bookkeeping carry over from one config to the next.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, file := range args {
+			if _, err := os.Stat(file); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := validateBatch(); err != nil {
+			log.Fatal(err)
+		}
+		configFiles = args[:1]
+		configs := []pullpanda.Config{setupRun(cmd)}
+		for _, file := range args[1:] {
+			config, err := loadConfigFile(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			configs = append(configs, config)
+		}
+
+		// Every config is reported even when one fails, and the first
+		// failure decides the exit status
+		status := exitOK
+		for i, config := range configs {
+			printBatchHeading(args[i], i)
+			code := runReport(config)
+			if status == exitOK {
+				status = code
+			}
+			if code == exitInterrupted {
+				break
+			}
+		}
+		if status != exitOK {
+			os.Exit(status)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+}
+
+// validateBatch rejects the flags whose output can't hold several reports.
+func validateBatch() error {
+	if outputFormat != "table" && outputFormat != "markdown" {
+		return fmt.Errorf("batch only supports --output table and markdown, got %q", outputFormat)
+	}
+	if outputFile != "" || watchInterval > 0 {
+		return fmt.Errorf("batch can't be combined with --output-file or --watch")
+	}
+	return nil
+}
+
+// printBatchHeading introduces the report of the i-th config file.
+func printBatchHeading(file string, i int) {
+	if i > 0 {
+		fmt.Println()
+	}
+	if outputFormat == "markdown" {
+		fmt.Printf("## %s\n\n", file)
+		return
+	}
+	fmt.Printf("%s:\n", file)
+}
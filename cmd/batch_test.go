@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// batchConfigs are the configs of the batch benchmarks, two teams of a few
+// handles each.
+var batchConfigs = []pullpanda.Config{
+	{Handles: []pullpanda.Handle{{Handle: "octocat"}, {Handle: "hubot"}, {Handle: "monalisa"}}, Orgs: []string{"octo"}, Statuses: []string{"merged", "open"}},
+	{Handles: []pullpanda.Handle{{Handle: "defunkt"}, {Handle: "mojombo"}, {Handle: "pjhyett"}}, Orgs: []string{"octo"}, Statuses: []string{"merged", "open"}},
+}
+
+// batchServer answers every search with one PR and counts the connections
+// opened to it.
+func batchServer(b *testing.B) (*httptest.Server, *int64) {
+	b.Helper()
+	var conns int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":1,"items":[{"url":"https://api.github.com/repos/octo/api/issues/1","number":1,"repository_url":"https://api.github.com/repos/octo/api"}]}`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	srv.Start()
+	b.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+func benchmarkBatch(b *testing.B, shared bool) {
+	srv, conns := batchServer(b)
+	token, apiURL = "test-token", srv.URL
+	defer func() { token, apiURL, apiClient = "", "", nil }()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if shared {
+			apiClient = newClient()
+		}
+		for _, config := range batchConfigs {
+			if !shared {
+				apiClient = newClient()
+			}
+			if result := fetchAllPRs(config, pullpanda.DateRange{}); len(result.Failures) > 0 {
+				b.Fatal(failuresError(result))
+			}
+			if !shared {
+				apiClient.HTTPClient.CloseIdleConnections()
+			}
+		}
+		apiClient.HTTPClient.CloseIdleConnections()
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(conns))/float64(b.N), "conns/op")
+}
+
+// BenchmarkBatchSharedClient runs the configs like batch does, with one
+// client for all of them.
+func BenchmarkBatchSharedClient(b *testing.B) { benchmarkBatch(b, true) }
+
+// BenchmarkBatchClientPerConfig runs the configs like separate invocations
+// would, with a new client each.
+func BenchmarkBatchClientPerConfig(b *testing.B) { benchmarkBatch(b, false) }
+
+// TestLoadConfigFileChecksEveryConfig loads the later configs of a batch,
+// checking they are rejected for the same mistakes as the first one.
+func TestLoadConfigFileChecksEveryConfig(t *testing.T) {
+	apiTokens = []string{"first", "second"}
+	defer func() { apiTokens = nil }()
+
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{name: "valid", yaml: "handles: [octocat]\norgs: [octo]\n"},
+		{name: "same tokens", yaml: "handles: [octocat]\ntokens: [first, second]\n"},
+		{name: "no handles", yaml: "handles: []\norgs: [octo]\n", want: "no handles configured"},
+		{name: "scope without org", yaml: "handles: [octocat]\nscopes:\n  - repos: [api]\n", want: "scope 1 has no org"},
+		{name: "tokens of its own", yaml: "handles: [octocat]\ntokens: [other]\n", want: "sets tokens of its own"},
+		{name: "unknown status", yaml: "handles: [octocat]\nstatuses: [bogus]\n", want: "unknown statuses"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "team.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadConfigFile(path)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("loadConfigFile failed: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("loadConfigFile = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// breakdownBuckets splits window into consecutive weekly or monthly buckets.
+// Weeks start on the window's start date; months follow the calendar, with the
+// first and last clipped to the window. An open end means today.
+func breakdownBuckets(window pullpanda.DateRange, mode string) ([]pullpanda.DateRange, error) {
+	if mode != "weekly" && mode != "monthly" {
+		return nil, fmt.Errorf("unknown breakdown %q, expected weekly or monthly", mode)
+	}
+	if window.Start == "" {
+		return nil, fmt.Errorf("--breakdown needs a start date from --start-date or --duration")
+	}
+	start, err := time.Parse("2006-01-02", window.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q: %w", window.Start, err)
+	}
+	end := now()
+	if window.End != "" {
+		if end, err = time.Parse("2006-01-02", window.End); err != nil {
+			return nil, fmt.Errorf("invalid end date %q: %w", window.End, err)
+		}
+	}
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if end.Before(start) {
+		return nil, fmt.Errorf("end date %s is before start date %s", window.End, window.Start)
+	}
+
+	var buckets []pullpanda.DateRange
+	for bucketStart := start; !bucketStart.After(end); {
+		next := bucketStart.AddDate(0, 0, 7)
+		if mode == "monthly" {
+			next = time.Date(bucketStart.Year(), bucketStart.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+
+		bucketEnd := next.AddDate(0, 0, -1)
+		if bucketEnd.After(end) {
+			bucketEnd = end
+		}
+		buckets = append(buckets, pullpanda.DateRange{
+			Start:    bucketStart.Format("2006-01-02"),
+			End:      bucketEnd.Format("2006-01-02"),
+			Location: window.Location,
+		})
+		bucketStart = next
+	}
+	return buckets, nil
+}
+
+// validateBreakdown rejects the flags whose output the breakdown table has
+// no room for.
+func validateBreakdown() error {
+	if breakdown == "" {
+		return nil
+	}
+	if breakdown != "weekly" && breakdown != "monthly" {
+		return fmt.Errorf("unknown breakdown %q, expected weekly or monthly", breakdown)
+	}
+	if outputFormat != "table" {
+		return fmt.Errorf("--breakdown only supports --output table, got %q", outputFormat)
+	}
+	if stepSummary || sqlitePath != "" || explain || summaryLine || showSparkline || showPRs {
+		return fmt.Errorf("--breakdown can't be combined with --step-summary, --sqlite, --explain, --summary-line, --sparkline or --show-prs")
+	}
+	if minPRs > 0 || top > 0 {
+		return fmt.Errorf("--breakdown can't be combined with --min-prs or --top")
+	}
+	return nil
+}
+
+// fetchBreakdown fetches every bucket and returns the run over the whole
+// window, each summary counting the PRs of all buckets, alongside the total
+// of every bucket per summary. A handle failing in one bucket is a failure
+// of the run and isn't fetched for the buckets after it. An interrupt leaves
+// every handle still being fetched unfinished and none in the summaries,
+// since no handle has all its buckets.
+func fetchBreakdown(config pullpanda.Config, buckets []pullpanda.DateRange) (pullpanda.RunResult, [][]int) {
+	run := pullpanda.RunResult{Failures: make(map[string]error)}
+	summaries := make(map[string]*pullpanda.Summary)
+	totals := make(map[string][]int)
+
+	for _, bucket := range buckets {
+		result := fetchAllPRs(config, bucket)
+		run.Warnings = append(run.Warnings, result.Warnings...)
+		for handle, err := range result.Failures {
+			run.Failures[handle] = err
+		}
+		if len(result.Unfinished) > 0 {
+			for _, handle := range config.Handles {
+				if _, ok := run.Failures[handle.Handle]; !ok {
+					run.Unfinished = append(run.Unfinished, handle.Handle)
+				}
+			}
+			return run, nil
+		}
+		for i, total := range summaryTotals(result.Summaries) {
+			summary := result.Summaries[i]
+			totals[summary.Handle] = append(totals[summary.Handle], total)
+			combined, ok := summaries[summary.Handle]
+			if !ok {
+				combined = &summary
+				combined.Counts = make(map[string]int)
+				summaries[summary.Handle] = combined
+			}
+			for status, count := range result.Summaries[i].Counts {
+				combined.Counts[status] += count
+			}
+		}
+
+		var handles []pullpanda.Handle
+		for _, handle := range config.Handles {
+			if _, ok := run.Failures[handle.Handle]; !ok {
+				handles = append(handles, handle)
+			}
+		}
+		config.Handles = handles
+	}
+
+	for _, handle := range config.Handles {
+		if summary, ok := summaries[handle.Handle]; ok {
+			run.Summaries = append(run.Summaries, *summary)
+		}
+	}
+	run.Summaries = sortedSummaries(run.Summaries, config)
+	matrix := make([][]int, len(run.Summaries))
+	for i, summary := range run.Summaries {
+		matrix[i] = totals[summary.Handle]
+	}
+	return run, matrix
+}
+
+// printBreakdownTable renders handles as rows and buckets as columns.
+func printBreakdownTable(w io.Writer, summaries []pullpanda.Summary, buckets []pullpanda.DateRange, matrix [][]int, mode string) {
+	header := []string{"Handle"}
+	for _, bucket := range buckets {
+		label := bucket.Start
+		if mode == "monthly" {
+			label = bucket.Start[:7]
+		}
+		header = append(header, label)
+	}
+	header = append(header, "Total")
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+
+	columnTotals := make([]int, len(buckets))
+	grandTotal := 0
+	for i, summary := range summaries {
+		row := []string{summary.Label()}
+		total := 0
+		for j, count := range matrix[i] {
+			row = append(row, formatCount(count))
+			columnTotals[j] += count
+			total += count
+		}
+		grandTotal += total
+		table.Append(append(row, formatCount(total)))
+	}
+
+	footer := []string{"Total"}
+	for _, total := range columnTotals {
+		footer = append(footer, formatCount(total))
+	}
+	table.SetFooter(append(footer, formatCount(grandTotal)))
+	table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestBreakdownBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		window pullpanda.DateRange
+		mode   string
+		want   []string
+	}{
+		{
+			name:   "weekly",
+			window: pullpanda.DateRange{Start: "2024-01-03", End: "2024-01-20"},
+			mode:   "weekly",
+			want:   []string{"2024-01-03..2024-01-09", "2024-01-10..2024-01-16", "2024-01-17..2024-01-20"},
+		},
+		{
+			name:   "weekly single day",
+			window: pullpanda.DateRange{Start: "2024-01-03", End: "2024-01-03"},
+			mode:   "weekly",
+			want:   []string{"2024-01-03..2024-01-03"},
+		},
+		{
+			name:   "monthly",
+			window: pullpanda.DateRange{Start: "2024-01-15", End: "2024-03-10"},
+			mode:   "monthly",
+			want:   []string{"2024-01-15..2024-01-31", "2024-02-01..2024-02-29", "2024-03-01..2024-03-10"},
+		},
+		{
+			name:   "monthly across a year",
+			window: pullpanda.DateRange{Start: "2023-12-01", End: "2024-01-31"},
+			mode:   "monthly",
+			want:   []string{"2023-12-01..2023-12-31", "2024-01-01..2024-01-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets, err := breakdownBuckets(tt.window, tt.mode)
+			if err != nil {
+				t.Fatalf("breakdownBuckets failed: %v", err)
+			}
+			var got []string
+			for _, bucket := range buckets {
+				got = append(got, bucket.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buckets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakdownBucketQueries(t *testing.T) {
+	buckets, err := breakdownBuckets(pullpanda.DateRange{Start: "2024-01-03", End: "2024-01-12"}, "weekly")
+	if err != nil {
+		t.Fatalf("breakdownBuckets failed: %v", err)
+	}
+	want := []string{
+		" merged:>=2024-01-03 merged:<=2024-01-09",
+		" merged:>=2024-01-10 merged:<=2024-01-12",
+	}
+	if len(buckets) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(buckets), len(want))
+	}
+	for i, bucket := range buckets {
+		if got := bucket.Qualifiers("merged"); got != want[i] {
+			t.Errorf("bucket %d qualifiers = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestBreakdownBucketsRejectsBadWindows(t *testing.T) {
+	tests := []struct {
+		name   string
+		window pullpanda.DateRange
+		mode   string
+	}{
+		{name: "unknown mode", window: pullpanda.DateRange{Start: "2024-01-01", End: "2024-01-31"}, mode: "daily"},
+		{name: "no start", window: pullpanda.DateRange{End: "2024-01-31"}, mode: "weekly"},
+		{name: "bad start", window: pullpanda.DateRange{Start: "01/01/2024"}, mode: "weekly"},
+		{name: "end before start", window: pullpanda.DateRange{Start: "2024-02-01", End: "2024-01-31"}, mode: "monthly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := breakdownBuckets(tt.window, tt.mode); err == nil {
+				t.Error("breakdownBuckets succeeded, want an error")
+			}
+		})
+	}
+}
+
+// TestFetchBreakdownKeepsFailures fetches a breakdown where one handle's
+// searches fail, checking it is reported as a failure while the other gets
+// its row and its counts summed over the buckets.
+func TestFetchBreakdownKeepsFailures(t *testing.T) {
+	searches := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		if strings.Contains(q, "author:hubot") {
+			http.Error(w, `{"message":"Validation Failed"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		searches[q]++
+		fmt.Fprint(w, `{"total_count":2,"items":[]}`)
+	}))
+	defer srv.Close()
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	apiClient.CountOnly = true
+	defer func() { apiClient = nil }()
+
+	config := pullpanda.Config{
+		Handles:  []pullpanda.Handle{{Handle: "hubot"}, {Handle: "octocat"}},
+		Statuses: []string{"merged"},
+	}
+	buckets, err := breakdownBuckets(pullpanda.DateRange{Start: "2024-01-01", End: "2024-01-14"}, "weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, matrix := fetchBreakdown(config, buckets)
+
+	if _, ok := result.Failures["hubot"]; !ok {
+		t.Errorf("failures = %v, want hubot", result.Failures)
+	}
+	if len(result.Summaries) != 1 || result.Summaries[0].Handle != "octocat" {
+		t.Fatalf("summaries = %+v, want octocat only", result.Summaries)
+	}
+	if got := result.Summaries[0].Counts["merged"]; got != 4 {
+		t.Errorf("octocat merged = %d, want 4 over both weeks", got)
+	}
+	if want := [][]int{{2, 2}}; !reflect.DeepEqual(matrix, want) {
+		t.Errorf("matrix = %v, want %v", matrix, want)
+	}
+}
+
+func TestValidateBreakdownRejectsUnsupportedFlags(t *testing.T) {
+	breakdown = "weekly"
+	defer func() { breakdown, outputFormat, sqlitePath, top = "", "table", "", 0 }()
+
+	outputFormat = "table"
+	if err := validateBreakdown(); err != nil {
+		t.Errorf("validateBreakdown rejected a plain table: %v", err)
+	}
+	outputFormat = "json"
+	if err := validateBreakdown(); err == nil {
+		t.Error("validateBreakdown accepted --output json")
+	}
+	outputFormat, sqlitePath = "table", "counts.db"
+	if err := validateBreakdown(); err == nil {
+		t.Error("validateBreakdown accepted --sqlite")
+	}
+	sqlitePath, top = "", 3
+	if err := validateBreakdown(); err == nil {
+		t.Error("validateBreakdown accepted --top")
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// apiClient is the client every command fetches through, set up by setupRun
+// so caches and rate-limit bookkeeping are shared across all its requests.
+var apiClient *pullpanda.Client
+
+// titleRegexp is the compiled --title-match pattern, nil when unset.
+var titleRegexp *regexp.Regexp
+
+// newClient builds the pullpanda client from the flags.
+func newClient() *pullpanda.Client {
+	client := pullpanda.NewClient(token)
+	if len(apiTokens) > 1 {
+		client.Tokens = apiTokens
+	}
+	client.BaseURL = apiURL
+	client.HTTPClient = newHTTPClient()
+	client.CacheDir = cacheDir
+	client.CacheTTL = cacheTTL
+	if userAgentFlag != "" {
+		client.UserAgent = userAgentFlag
+	}
+	client.Accept = acceptFlag
+	if appAuth != nil {
+		appAuth.BaseURL = client.BaseURL
+		appAuth.HTTPClient = client.HTTPClient
+		appAuth.UserAgent = client.UserAgent
+		client.TokenSource = appAuth.Token
+	}
+	client.CountOnly = countOnly
+	client.Limit = limit
+	client.PageSize = pageSize
+	client.CodeownersTeam = codeownersTeam
+	client.PathFilter = pathFilter
+	client.TitleMatch = titleRegexp
+	client.MatchAffectsCounts = matchAffectsCounts
+	client.ExcludeDrafts = excludeDrafts
+	client.OnlyDrafts = onlyDrafts
+	client.QueryExtra = queryExtra
+	client.IncludeIssues = includeIssues
+	client.IncludeReviewComments = includeReviewComments
+	client.IncludeCommits = includeCommits
+	client.IncludeCoAuthored = includeCoAuthored
+	client.SkipInactiveRepos = skipInactiveRepos
+	if excludeForks {
+		client.Forks = pullpanda.ExcludeForks
+	} else if onlyForks {
+		client.Forks = pullpanda.OnlyForks
+	}
+	client.UseGraphQL = useGraphQL
+	if enableLog {
+		client.Logger = log.Default()
+	}
+	if progressEnabled() {
+		client.OnProgress = printProgress(os.Stderr)
+	}
+	if tokenExpiryWarn > 0 {
+		client.OnTokenExpiry = warnTokenExpiry(tokenExpiryWarn)
+	}
+	return client
+}
+
+// fetchAllPRs fetches every handle of config within window, logging the
+// warnings of the run.
+func fetchAllPRs(config pullpanda.Config, window pullpanda.DateRange) pullpanda.RunResult {
+	return fetchWith(apiClient.WithWindow(window), config)
+}
+
+// fetchWith is fetchAllPRs for a client of its own, such as one only
+// counting.
+func fetchWith(client *pullpanda.Client, config pullpanda.Config) pullpanda.RunResult {
+	// An interrupt cancels the fetch, and the handles done so far are still
+	// rendered
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := client.Fetch(ctx, config)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+	if len(result.Unfinished) > 0 {
+		warnf("interrupted, showing the %d handle(s) fetched so far", len(result.Summaries))
+	}
+	for _, warning := range result.Warnings {
+		warnf("%s", warning)
+	}
+	return result
+}
+
+// printRateLimit writes a one-line summary of the remaining rate limit.
+func printRateLimit(w io.Writer) {
+	rateLimit, ok := apiClient.RateLimit()
+	if !ok {
+		fmt.Fprintln(w, "Rate limit: no rate-limit headers received")
+		return
+	}
+	fmt.Fprintf(w, "Rate limit: %d requests remaining, resets at %s\n", rateLimit.Remaining, rateLimit.Reset.Format(time.RFC3339))
+}
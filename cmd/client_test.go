@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestPrintRateLimit(t *testing.T) {
+	rateLimitServer(t, 4321)
+
+	var before bytes.Buffer
+	printRateLimit(&before)
+	if got := before.String(); got != "Rate limit: no rate-limit headers received\n" {
+		t.Errorf("before any request printed %q", got)
+	}
+
+	fetchAllPRs(pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}, pullpanda.DateRange{})
+	var after bytes.Buffer
+	printRateLimit(&after)
+	if got := after.String(); !strings.HasPrefix(got, "Rate limit: 4321 requests remaining, resets at ") {
+		t.Errorf("printed %q, want the 4321 remaining requests from the headers", got)
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// faint is the SGR code for dimmed text, which tablewriter has no constant for.
+const faint = 2
+
+var colorModes = []string{"auto", "always", "never"}
+
+func validateColorMode(mode string) error {
+	for _, m := range colorModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color mode %q, expected auto, always or never", mode)
+}
+
+// useColor decides whether the table gets ANSI colors. In auto mode colors are
+// only used when the report goes to a terminal and NO_COLOR is not set.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return outputFile == "" && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// appendColoredRows adds rows to table with the top contributors highlighted
+// and zero counts dimmed. totals holds each row's total; rows past the end of
+// totals, such as failed handles, are never highlighted.
+func appendColoredRows(table *tablewriter.Table, rows [][]string, totals []int) {
+	highest := 0
+	for _, total := range totals {
+		if total > highest {
+			highest = total
+		}
+	}
+
+	for i, row := range rows {
+		colors := make([]tablewriter.Colors, len(row))
+		for j, cell := range row {
+			switch {
+			case highest > 0 && i < len(totals) && totals[i] == highest:
+				colors[j] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor}
+			case j > 0 && cell == emptyCell():
+				colors[j] = tablewriter.Colors{faint}
+			}
+		}
+		table.Rich(row, colors)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+func TestAppendColoredRowsDimsEmptyCells(t *testing.T) {
+	for _, mode := range []string{"zero", "dash", "n/a"} {
+		t.Run(mode, func(t *testing.T) {
+			emptyAs = mode
+			defer func() { emptyAs = "" }()
+
+			var out bytes.Buffer
+			table := tablewriter.NewWriter(&out)
+			appendColoredRows(table, [][]string{
+				{"octocat", formatCount(3)},
+				{"hubot", formatCount(0)},
+			}, []int{3, 0})
+			table.Render()
+
+			dimmed := "\x1b[2m" + emptyCell() + "\x1b[0m"
+			if !strings.Contains(out.String(), dimmed) {
+				t.Errorf("zero cell isn't dimmed as %q in:\n%s", dimmed, out.String())
+			}
+			if !strings.Contains(out.String(), "\x1b[1;32m3\x1b[0m") {
+				t.Errorf("top total isn't highlighted in:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestPrintSummaryTableColorModes(t *testing.T) {
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 3}},
+		{Handle: "hubot", Counts: map[string]int{"merged": 0}},
+	}}
+	tests := []struct {
+		mode    string
+		noColor bool
+		want    bool
+	}{
+		{mode: "never", want: false},
+		{mode: "always", want: true},
+		{mode: "always", noColor: true, want: true},
+		{mode: "auto", noColor: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if tt.noColor {
+				t.Setenv("NO_COLOR", "1")
+			}
+			colorMode = tt.mode
+			defer func() { colorMode = "auto" }()
+
+			var out bytes.Buffer
+			printSummaryTable(&out, result, []string{"merged"})
+			if got := strings.Contains(out.String(), "\x1b["); got != tt.want {
+				t.Errorf("ANSI codes in output = %v, want %v:\n%s", got, tt.want, out.String())
+			}
+		})
+	}
+}
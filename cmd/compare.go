@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var (
+	periodA string
+	periodB string
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare contributions between two time periods",
+	Long: `Compare fetches PRs for two periods and shows, per handle, the total for
+each period and the change from period A (the baseline) to period B.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := setupRun(cmd)
+		windowA, err := parseDateRange("--period-a", periodA)
+		if err != nil {
+			log.Fatal(err)
+		}
+		windowB, err := parseDateRange("--period-b", periodB)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Rows are matched by index, so a failed handle fails the comparison
+		resultA := fetchAllPRs(config, windowA)
+		if err := failuresError(resultA); err != nil {
+			fatalFetch(err)
+		}
+		resultB := fetchAllPRs(config, windowB)
+		if err := failuresError(resultB); err != nil {
+			fatalFetch(err)
+		}
+
+		printComparisonTable(resultA.Summaries, resultB.Summaries, windowA, windowB)
+		if strictFailure() {
+			os.Exit(exitWarnings)
+		}
+	},
+}
+
+func init() {
+	compareCmd.Flags().StringVar(&periodA, "period-a", "", "Baseline period as YYYY-MM-DD..YYYY-MM-DD")
+	compareCmd.Flags().StringVar(&periodB, "period-b", "", "Period to compare against the baseline as YYYY-MM-DD..YYYY-MM-DD")
+	compareCmd.MarkFlagRequired("period-a")
+	compareCmd.MarkFlagRequired("period-b")
+	rootCmd.AddCommand(compareCmd)
+}
+
+// printComparisonTable renders one row per handle. Both summary slices come
+// from the same config, so rows line up by index.
+func printComparisonTable(summariesA, summariesB []pullpanda.Summary, windowA, windowB pullpanda.DateRange) {
+	totalsA := summaryTotals(summariesA)
+	totalsB := summaryTotals(summariesB)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Handle", "A " + windowA.String(), "B " + windowB.String(), "Delta"})
+
+	grandA, grandB := 0, 0
+	for i, summary := range summariesA {
+		table.Append([]string{summary.Label(), formatCount(totalsA[i]), formatCount(totalsB[i]), formatDelta(totalsA[i], totalsB[i])})
+		grandA += totalsA[i]
+		grandB += totalsB[i]
+	}
+
+	table.SetFooter([]string{"Total", formatCount(grandA), formatCount(grandB), formatDelta(grandA, grandB)})
+	table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
+
+// formatDelta formats the change from a to b as a signed count followed by
+// the percentage relative to a, or "-" when a is zero.
+func formatDelta(a, b int) string {
+	delta := b - a
+	if a == 0 {
+		return fmt.Sprintf("%+d (-)", delta)
+	}
+	return fmt.Sprintf("%+d (%+.1f%%)", delta, float64(delta)/float64(a)*100)
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+func TestFormatDelta(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want string
+	}{
+		{a: 10, b: 15, want: "+5 (+50.0%)"},
+		{a: 10, b: 5, want: "-5 (-50.0%)"},
+		{a: 4, b: 4, want: "+0 (+0.0%)"},
+		{a: 3, b: 4, want: "+1 (+33.3%)"},
+		{a: 3, b: 0, want: "-3 (-100.0%)"},
+		{a: 0, b: 7, want: "+7 (-)"},
+		{a: 0, b: 0, want: "+0 (-)"},
+	}
+	for _, tt := range tests {
+		if got := formatDelta(tt.a, tt.b); got != tt.want {
+			t.Errorf("formatDelta(%d, %d) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// applyFlagOverrides replaces the config's handles, orgs and repos with the
+// ones given as flags, or merges them in with --merge-scope. --tokens always
+// replaces the config's tokens.
+func applyFlagOverrides(config pullpanda.Config) pullpanda.Config {
+	if len(tokensFlag) > 0 {
+		config.Tokens = tokensFlag
+	}
+	var handles []pullpanda.Handle
+	for _, h := range handlesFlag {
+		handles = append(handles, pullpanda.Handle{Handle: h})
+	}
+	if mergeScope {
+		return config.Merge(pullpanda.Config{Handles: handles, Orgs: orgsFlag, Repos: reposFlag})
+	}
+
+	if len(handles) > 0 {
+		config.Handles = handles
+	}
+	if len(orgsFlag) > 0 {
+		config.Orgs = orgsFlag
+	}
+	if len(reposFlag) > 0 {
+		config.Repos = reposFlag
+	}
+	return config
+}
+
+// loadRunConfig loads the config files with the flag overrides applied.
+// Without an explicit --config, handles or teams given as flags are enough,
+// and a missing config file leaves them counting merged PRs.
+func loadRunConfig(explicitConfig bool) (pullpanda.Config, error) {
+	var config pullpanda.Config
+	err := resolveConfigFiles()
+	if err == nil {
+		config, err = configLoader().Load(configFiles...)
+	}
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || explicitConfig || (len(handlesFlag) == 0 && len(teamsFlag) == 0) {
+			return config, err
+		}
+		config = pullpanda.Config{Statuses: []string{"merged"}}
+	}
+	return applyFlagOverrides(config), nil
+}
+
+// defaultBots are the accounts --exclude-bots leaves out unless --bots
+// replaces them.
+var defaultBots = []string{"app/dependabot", "app/renovate", "app/github-actions", "app/pre-commit-ci"}
+
+// excludeBots adds the --bots accounts to the config's excludeAuthors when
+// --exclude-bots is set.
+func excludeBots(config pullpanda.Config) pullpanda.Config {
+	if !excludeBotsFlag {
+		return config
+	}
+	return config.Merge(pullpanda.Config{ExcludeAuthors: botsFlag})
+}
+
+// expandTeams adds the members of the --team teams to the handles. Like
+// --handles they replace the config's handles unless --merge-scope is set.
+func expandTeams(config pullpanda.Config) (pullpanda.Config, error) {
+	if len(teamsFlag) == 0 {
+		return config, nil
+	}
+	var members []pullpanda.Handle
+	if len(handlesFlag) > 0 || mergeScope {
+		members = config.Handles
+	}
+	for _, team := range teamsFlag {
+		handles, err := apiClient.TeamMembers(context.Background(), team)
+		if err != nil {
+			return config, err
+		}
+		members = append(members, handles...)
+	}
+	config.Handles = pullpanda.Config{}.Merge(pullpanda.Config{Handles: members}).Handles
+	return config, nil
+}
+
+// trimHandles trims whitespace around the handles and drops the blank ones.
+func trimHandles(handles []pullpanda.Handle) []pullpanda.Handle {
+	var trimmed []pullpanda.Handle
+	for _, h := range handles {
+		h.Handle = strings.TrimSpace(h.Handle)
+		if h.Handle != "" {
+			trimmed = append(trimmed, h)
+		}
+	}
+	return trimmed
+}
+
+// checkConfig fails on the errors validate reports for config.
+func checkConfig(config pullpanda.Config) error {
+	if errs, _ := validateConfig(config); len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// logConfig logs config with --log, its tokens redacted.
+func logConfig(config pullpanda.Config) {
+	if !enableLog {
+		return
+	}
+	if len(config.Tokens) > 0 {
+		config.Tokens = []string{fmt.Sprintf("(%d redacted)", len(config.Tokens))}
+	}
+	log.Printf("Loaded config: %+v\n", config)
+}
+
+// configLoader returns the config loader set up by the flags.
+func configLoader() pullpanda.ConfigLoader {
+	return pullpanda.ConfigLoader{StrictEnv: strictEnv}
+}
+
+// normalizedHandles lowercases handles, which GitHub treats
+// case-insensitively, and merges the ones differing only in case into the
+// first, taking a name or orgs and repos from later ones like Config.Merge.
+// A handle without a name keeps its first spelling as its row label.
+func normalizedHandles(handles []pullpanda.Handle) []pullpanda.Handle {
+	index := make(map[string]int)
+	var normalized []pullpanda.Handle
+	for _, h := range handles {
+		canonical := strings.ToLower(h.Handle)
+		i, ok := index[canonical]
+		if !ok {
+			index[canonical] = len(normalized)
+			if h.Name == "" && h.Handle != canonical {
+				h.Name = h.Handle
+			}
+			h.Handle = canonical
+			normalized = append(normalized, h)
+			continue
+		}
+		if h.Name != "" {
+			normalized[i].Name = h.Name
+		}
+		if len(h.Orgs) > 0 || len(h.Repos) > 0 {
+			normalized[i].Orgs, normalized[i].Repos = h.Orgs, h.Repos
+		}
+	}
+	return normalized
+}
+
+// defaultConfigPaths are the config files looked for, in order, when --config
+// isn't given.
+func defaultConfigPaths() []string {
+	paths := []string{"config.yaml"}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	home, err := os.UserHomeDir()
+	if configHome == "" && err == nil {
+		configHome = filepath.Join(home, ".config")
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "pullpanda", "config.yaml"))
+	}
+	if err == nil {
+		paths = append(paths, filepath.Join(home, ".pullpanda.yaml"))
+	}
+	return paths
+}
+
+// resolveConfigFiles sets configFiles to the first of defaultConfigPaths that
+// exists when no --config was given, and fails with a noConfigError when
+// none does.
+func resolveConfigFiles() error {
+	if len(configFiles) > 0 {
+		return nil
+	}
+	paths := defaultConfigPaths()
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			configFiles = []string{path}
+			if enableLog {
+				log.Printf("Using config file %s\n", path)
+			}
+			return nil
+		}
+	}
+	return noConfigError{paths}
+}
+
+// noConfigError is the error of resolveConfigFiles, matching os.ErrNotExist.
+type noConfigError struct {
+	paths []string
+}
+
+func (e noConfigError) Error() string {
+	return fmt.Sprintf("no config file found, looked for %s; pass --config or --handles", strings.Join(e.paths, ", "))
+}
+
+func (e noConfigError) Is(target error) bool {
+	return target == os.ErrNotExist
+}
+
+// requireHandles fails when the config has no usable handles, which would
+// otherwise render a table with nothing but a footer.
+func requireHandles(config pullpanda.Config) (pullpanda.Config, error) {
+	config.Handles = trimHandles(config.Handles)
+	if normalizeHandles {
+		config.Handles = normalizedHandles(config.Handles)
+	}
+	if len(config.Handles) == 0 {
+		where := "the config"
+		if len(configFiles) > 0 {
+			where = strings.Join(configFiles, ", ")
+		}
+		return config, fmt.Errorf("no handles configured; list GitHub handles under handles: in %s or pass --handles", where)
+	}
+	return config, nil
+}
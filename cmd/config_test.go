@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestApplyFlagOverrides(t *testing.T) {
+	config := pullpanda.Config{
+		Handles: []pullpanda.Handle{{Handle: "octocat"}},
+		Orgs:    []string{"octo"},
+		Repos:   []string{"octo/api"},
+	}
+	tests := []struct {
+		name    string
+		merge   bool
+		handles []string
+		orgs    []string
+		repos   []string
+		want    pullpanda.Config
+	}{
+		{
+			name: "no flags",
+			want: config,
+		},
+		{
+			name:  "override",
+			orgs:  []string{"hub"},
+			repos: []string{"hub/web"},
+			want: pullpanda.Config{
+				Handles: []pullpanda.Handle{{Handle: "octocat"}},
+				Orgs:    []string{"hub"},
+				Repos:   []string{"hub/web"},
+			},
+		},
+		{
+			name:    "merge",
+			merge:   true,
+			handles: []string{"hubot", "octocat"},
+			orgs:    []string{"hub", "octo"},
+			repos:   []string{"hub/web"},
+			want: pullpanda.Config{
+				Handles: []pullpanda.Handle{{Handle: "octocat"}, {Handle: "hubot"}},
+				Orgs:    []string{"octo", "hub"},
+				Repos:   []string{"octo/api", "hub/web"},
+			},
+		},
+	}
+	defer func() { mergeScope, handlesFlag, orgsFlag, reposFlag = false, nil, nil, nil }()
+	for _, tt := range tests {
+		mergeScope, handlesFlag, orgsFlag, reposFlag = tt.merge, tt.handles, tt.orgs, tt.repos
+		got := applyFlagOverrides(config)
+		if !reflect.DeepEqual(got.Handles, tt.want.Handles) || !reflect.DeepEqual(got.Orgs, tt.want.Orgs) || !reflect.DeepEqual(got.Repos, tt.want.Repos) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestLoadRunConfigWithoutConfigFile runs from a directory, and a home,
+// without any config file, so only the flags set the handles and repos.
+func TestLoadRunConfigWithoutConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func() { configFiles, handlesFlag, reposFlag = nil, nil, nil }()
+
+	if _, err := loadRunConfig(false); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("without a config file or flags, err = %v, want a missing config", err)
+	}
+
+	handlesFlag, reposFlag = []string{"octocat"}, []string{"octo/api"}
+	config, err := loadRunConfig(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pullpanda.Config{
+		Handles:  []pullpanda.Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/api"},
+		Statuses: []string{"merged"},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+
+	configFiles = []string{filepath.Join(dir, "missing.yaml")}
+	if _, err := loadRunConfig(true); err == nil {
+		t.Error("a missing --config file wasn't reported")
+	}
+}
+
+// TestResolveConfigFilesFindsDefaults adds the default config files one at a
+// time, from the last looked for to the first, and checks each new one wins.
+func TestResolveConfigFilesFindsDefaults(t *testing.T) {
+	home, work := t.TempDir(), t.TempDir()
+	xdg := filepath.Join(home, "xdg")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func() { configFiles = nil }()
+
+	want := []string{"config.yaml", filepath.Join(xdg, "pullpanda", "config.yaml"), filepath.Join(home, ".pullpanda.yaml")}
+	if got := defaultConfigPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("defaultConfigPaths = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if got := defaultConfigPaths()[1]; got != filepath.Join(home, ".config", "pullpanda", "config.yaml") {
+		t.Errorf("without XDG_CONFIG_HOME, looked for %q", got)
+	}
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	if err := os.MkdirAll(filepath.Join(xdg, "pullpanda"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		handle := fmt.Sprintf("handle%d", i)
+		if err := os.WriteFile(want[i], []byte("handles: ["+handle+"]\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		configFiles = nil
+		config, err := loadRunConfig(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(configFiles, want[i:i+1]) || len(config.Handles) != 1 || config.Handles[0].Handle != handle {
+			t.Errorf("with %s, used %q with handles %+v", want[i], configFiles, config.Handles)
+		}
+	}
+}
+
+func TestRequireHandles(t *testing.T) {
+	defer func() { configFiles = nil }()
+	tests := []struct {
+		name        string
+		files       []string
+		handles     []pullpanda.Handle
+		want        []pullpanda.Handle
+		errContains string
+	}{
+		{name: "none", errContains: "no handles configured; list GitHub handles under handles: in the config or pass --handles"},
+		{name: "blank", files: []string{"team.yaml"}, handles: []pullpanda.Handle{{Handle: "  "}, {Handle: ""}}, errContains: "under handles: in team.yaml or"},
+		{name: "trimmed", handles: []pullpanda.Handle{{Handle: " octocat "}, {Handle: ""}}, want: []pullpanda.Handle{{Handle: "octocat"}}},
+	}
+	for _, tt := range tests {
+		configFiles = tt.files
+		config, err := requireHandles(pullpanda.Config{Handles: tt.handles})
+		if tt.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("%s: err = %v, want one containing %q", tt.name, err, tt.errContains)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if !reflect.DeepEqual(config.Handles, tt.want) {
+			t.Errorf("%s: handles = %+v, want %+v", tt.name, config.Handles, tt.want)
+		}
+	}
+}
+
+func TestExcludeBots(t *testing.T) {
+	config := pullpanda.Config{ExcludeAuthors: []string{"octo-ci", "app/renovate"}}
+	defer func() { excludeBotsFlag, botsFlag = false, nil }()
+	tests := []struct {
+		exclude bool
+		bots    []string
+		want    []string
+	}{
+		{false, defaultBots, []string{"octo-ci", "app/renovate"}},
+		{true, defaultBots, []string{"octo-ci", "app/renovate", "app/dependabot", "app/github-actions", "app/pre-commit-ci"}},
+		{true, []string{"build-bot"}, []string{"octo-ci", "app/renovate", "build-bot"}},
+	}
+	for _, tt := range tests {
+		excludeBotsFlag, botsFlag = tt.exclude, tt.bots
+		if got := excludeBots(config).ExcludeAuthors; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--exclude-bots=%v --bots %q excluded %q, want %q", tt.exclude, tt.bots, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeHandlesMergesCaseDuplicates(t *testing.T) {
+	config := pullpanda.Config{Handles: []pullpanda.Handle{
+		{Handle: "OctoCat"},
+		{Handle: "hubot", Name: "Hubot"},
+		{Handle: "octocat", Repos: []string{"octo/api"}},
+		{Handle: "HUBOT", Orgs: []string{"hub"}},
+		{Handle: "octocat", Name: "The Octocat"},
+		{Handle: "monalisa"},
+	}}
+	want := []pullpanda.Handle{
+		{Handle: "octocat", Name: "The Octocat", Repos: []string{"octo/api"}},
+		{Handle: "hubot", Name: "Hubot", Orgs: []string{"hub"}},
+		{Handle: "monalisa"},
+	}
+
+	normalizeHandles = true
+	defer func() { normalizeHandles = false }()
+	got, err := requireHandles(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Handles, want) {
+		t.Errorf("handles = %+v, want %+v", got.Handles, want)
+	}
+
+	// Without a name, a merged handle keeps its first spelling as its label
+	got, _ = requireHandles(pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "OctoCat"}, {Handle: "octocat"}}})
+	if want := []pullpanda.Handle{{Handle: "octocat", Name: "OctoCat"}}; !reflect.DeepEqual(got.Handles, want) {
+		t.Errorf("handles = %+v, want %+v", got.Handles, want)
+	}
+
+	normalizeHandles = false
+	if got, _ := requireHandles(config); len(got.Handles) != len(config.Handles) {
+		t.Errorf("handles merged without --normalize-handles: %+v", got.Handles)
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// location is the --timezone the date flags are interpreted in.
+var location = time.UTC
+
+// now returns the current time in --timezone.
+func now() time.Time {
+	return time.Now().In(location)
+}
+
+// resolveDateKeyword turns the "now" keyword into the current date so scripted
+// runs can state "up to today" explicitly. Any other value is returned as is.
+func resolveDateKeyword(name, value string) string {
+	if !strings.EqualFold(value, "now") {
+		return value
+	}
+	resolved := now().Format("2006-01-02")
+	if enableLog {
+		log.Printf("Resolved %s %q to %s\n", name, value, resolved)
+	}
+	return resolved
+}
+
+// resolveDateRange builds the window from the date flags, with --duration
+// taking precedence over --start-date.
+func resolveDateRange() (pullpanda.DateRange, error) {
+	window := pullpanda.DateRange{
+		Start:    resolveDateKeyword("start-date", startDate),
+		End:      resolveDateKeyword("end-date", endDate),
+		Location: location,
+	}
+
+	// Calculate startDate if duration is provided
+	if duration != "" {
+		parsedDuration, err := parseDuration(duration)
+		if err != nil {
+			return window, fmt.Errorf("error parsing duration: %w", err)
+		}
+		startTime := now().Add(-parsedDuration)
+		window.Start = startTime.Format("2006-01-02")
+		if enableLog {
+			log.Printf("Parsed duration: %s, start date: %s\n", duration, window.Start)
+		}
+	}
+
+	if sinceRelease != "" {
+		if window.Start != "" || duration != "" {
+			return window, fmt.Errorf("--since-release can't be combined with --start-date or --duration")
+		}
+		if owner, name, ok := strings.Cut(sinceRelease, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return window, fmt.Errorf("invalid --since-release %q, expected owner/repo", sinceRelease)
+		}
+		release, err := apiClient.LatestRelease(context.Background(), sinceRelease)
+		if err != nil {
+			return window, err
+		}
+		window.Start = release.PublishedAt.In(location).Format("2006-01-02")
+		if enableLog {
+			log.Printf("Latest release of %s is %s, published %s, starting the range then\n", sinceRelease, release.Tag, release.PublishedAt.Format(time.RFC3339))
+		}
+	}
+
+	if window.Start == "" && defaultWindow != "" {
+		start, err := defaultStart(window.End, defaultWindow)
+		if err != nil {
+			return window, err
+		}
+		window.Start = start
+		if enableLog {
+			log.Printf("No start date given, --default-window %s starts the range at %s\n", defaultWindow, start)
+		}
+	}
+	if window.Start == "" && window.End != "" {
+		warnf("only --end-date is set, so every PR up to %s is counted; set --start-date, --duration or --default-window to bound the range", window.End)
+	}
+
+	return window, nil
+}
+
+// defaultStart returns the start of a window of the given length ending on
+// end, or today when end is empty.
+func defaultStart(end, length string) (string, error) {
+	parsedLength, err := parseDuration(length)
+	if err != nil {
+		return "", fmt.Errorf("error parsing --default-window: %w", err)
+	}
+	endTime := now()
+	if end != "" {
+		if endTime, err = time.ParseInLocation("2006-01-02", end, location); err != nil {
+			return "", fmt.Errorf("invalid end date %q: %w", end, err)
+		}
+	}
+	return endTime.Add(-parsedLength).Format("2006-01-02"), nil
+}
+
+// parseDateRange parses a "YYYY-MM-DD..YYYY-MM-DD" period. Either side may be
+// "now".
+func parseDateRange(name, value string) (pullpanda.DateRange, error) {
+	parts := strings.Split(value, "..")
+	if len(parts) != 2 {
+		return pullpanda.DateRange{}, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD..YYYY-MM-DD", name, value)
+	}
+
+	window := pullpanda.DateRange{
+		Start:    resolveDateKeyword(name, parts[0]),
+		End:      resolveDateKeyword(name, parts[1]),
+		Location: location,
+	}
+	for _, date := range []string{window.Start, window.End} {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return pullpanda.DateRange{}, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return window, nil
+}
+
+func parseDuration(duration string) (time.Duration, error) {
+	if len(duration) < 2 {
+		return 0, fmt.Errorf("invalid duration format")
+	}
+
+	unit := duration[len(duration)-1:]
+	value := duration[:len(duration)-1]
+
+	// Handle multi-character units like "mo" (months)
+	if unit == "o" && len(duration) > 2 && duration[len(duration)-2:] == "mo" {
+		unit = "mo"
+		value = duration[:len(duration)-2]
+	}
+
+	numValue, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+
+	switch unit {
+	case "s":
+		return time.Duration(numValue) * time.Second, nil
+	case "m":
+		return time.Duration(numValue) * time.Minute, nil
+	case "h":
+		return time.Duration(numValue) * time.Hour, nil
+	case "d":
+		return time.Duration(numValue) * 24 * time.Hour, nil
+	case "w":
+		return time.Duration(numValue) * 7 * 24 * time.Hour, nil
+	case "mo":
+		return time.Duration(numValue) * 30 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("invalid duration unit")
+	}
+}
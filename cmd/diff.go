@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff CONFIG_A CONFIG_B",
+	Short: "Compare the handles and totals of two configs",
+	Long: `Diff fetches PRs for the handles of two config files over the same date
+range and shows every handle of either side: handles only in A or only in B
+are flagged, and shared handles show the change from A to B.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, file := range args {
+			if _, err := os.Stat(file); err != nil {
+				log.Fatal(err)
+			}
+		}
+		configFiles = args[:1]
+		configA := setupRun(cmd)
+		configB, err := loadConfigFile(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		window, err := resolveDateRange()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		resultA := fetchAllPRs(configA, window)
+		if err := failuresError(resultA); err != nil {
+			fatalFetch(err)
+		}
+		resultB := fetchAllPRs(configB, window)
+		if err := failuresError(resultB); err != nil {
+			fatalFetch(err)
+		}
+
+		printDiffTable(diffRows(resultA.Summaries, resultB.Summaries), args[0], args[1])
+		if strictFailure() {
+			os.Exit(exitWarnings)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+// loadConfigFile loads and checks another config the way setupRun does the
+// first, with the same flag overrides, for diff and batch. The client is
+// shared, so it can't bring tokens of its own.
+func loadConfigFile(file string) (pullpanda.Config, error) {
+	config, err := configLoader().Load(file)
+	if err != nil {
+		return config, err
+	}
+	config = excludeBots(applyFlagOverrides(config))
+	if len(config.Tokens) > 0 && !slices.Equal(config.Tokens, apiTokens) {
+		return config, fmt.Errorf("%s sets tokens of its own, but every config is fetched with the tokens of the first", file)
+	}
+	if config, err = expandTeams(config); err != nil {
+		return config, err
+	}
+	if config, err = requireHandles(config); err != nil {
+		return config, err
+	}
+	if err := checkConfig(config); err != nil {
+		return config, fmt.Errorf("%s: %w", file, err)
+	}
+	logConfig(config)
+	return config, nil
+}
+
+// diffRow is one handle of a diff. A or B is nil when the handle is missing
+// from that side.
+type diffRow struct {
+	Label string
+	A, B  *int
+}
+
+// diffRows joins the totals of both sides by handle, ignoring case: A's
+// handles first in order, then the handles only in B.
+func diffRows(summariesA, summariesB []pullpanda.Summary) []diffRow {
+	totalsA := summaryTotals(summariesA)
+	totalsB := summaryTotals(summariesB)
+
+	var rows []diffRow
+	index := make(map[string]int)
+	for i, summary := range summariesA {
+		index[strings.ToLower(summary.Handle)] = len(rows)
+		rows = append(rows, diffRow{Label: summary.Label(), A: &totalsA[i]})
+	}
+	for i, summary := range summariesB {
+		if j, ok := index[strings.ToLower(summary.Handle)]; ok {
+			rows[j].B = &totalsB[i]
+			continue
+		}
+		rows = append(rows, diffRow{Label: summary.Label(), B: &totalsB[i]})
+	}
+	return rows
+}
+
+// printDiffTable renders the joined rows with each side's total, the change
+// for shared handles and which side a handle is unique to.
+func printDiffTable(rows []diffRow, nameA, nameB string) {
+	// Header cells are reformatted by tablewriter, so the files are named above
+	fmt.Printf("A: %s\nB: %s\n", nameA, nameB)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Handle", "A", "B", "Delta", "Only in"})
+
+	grandA, grandB := 0, 0
+	for _, row := range rows {
+		cells := []string{row.Label, "-", "-", "-", ""}
+		if row.A != nil {
+			cells[1] = formatCount(*row.A)
+			grandA += *row.A
+		}
+		if row.B != nil {
+			cells[2] = formatCount(*row.B)
+			grandB += *row.B
+		}
+		switch {
+		case row.A != nil && row.B != nil:
+			cells[3] = formatDelta(*row.A, *row.B)
+		case row.A != nil:
+			cells[4] = "A"
+		default:
+			cells[4] = "B"
+		}
+		table.Append(cells)
+	}
+
+	table.SetFooter([]string{"Total", formatCount(grandA), formatCount(grandB), formatDelta(grandA, grandB), ""})
+	table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestDiffRows(t *testing.T) {
+	a := []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 3}},
+		{Handle: "onlya", Counts: map[string]int{"merged": 1}},
+		{Handle: "Hubot", Name: "Hu Bot", Counts: map[string]int{"merged": 2, "open": 1}},
+	}
+	b := []pullpanda.Summary{
+		{Handle: "onlyb", Counts: map[string]int{"merged": 4}},
+		{Handle: "hubot", Counts: map[string]int{"merged": 5}},
+		{Handle: "octocat", Counts: map[string]int{}},
+	}
+	var got []string
+	for _, row := range diffRows(a, b) {
+		got = append(got, fmt.Sprintf("%s %s %s", row.Label, side(row.A), side(row.B)))
+	}
+	want := []string{"octocat 3 0", "onlya 1 -", "Hu Bot 3 5", "onlyb - 4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffRows = %q, want %q", got, want)
+	}
+}
+
+func side(total *int) string {
+	if total == nil {
+		return "-"
+	}
+	return fmt.Sprint(*total)
+}
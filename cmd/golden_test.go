@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, rewriting the file instead
+// with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run the test with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// goldenResult is the run the golden report tests render.
+func goldenResult() pullpanda.RunResult {
+	merged := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return pullpanda.RunResult{
+		Summaries: []pullpanda.Summary{
+			{
+				Handle:  "octocat",
+				Name:    "Mona",
+				Counts:  map[string]int{"merged": 2, "open": 1},
+				Repos:   []string{"octo/api", "octo/web"},
+				FirstPR: merged.AddDate(0, 0, -2),
+				LastPR:  merged.AddDate(0, 0, 1),
+				PRs: []pullpanda.PullRequest{
+					{
+						URL:        "https://api.github.com/repos/octo/api/issues/7",
+						HTMLURL:    "https://github.com/octo/api/pull/7",
+						Title:      "Fix [flaky] <retry> | loop",
+						Number:     7,
+						Repository: "octo/api",
+						State:      "closed",
+						Merged:     true,
+						CreatedAt:  merged.AddDate(0, 0, -2),
+						MergedAt:   &merged,
+					},
+					{
+						URL:        "https://api.github.com/repos/octo/web/issues/3",
+						Title:      "Add dark mode & themes",
+						Number:     3,
+						Repository: "octo/web",
+						State:      "open",
+						CreatedAt:  merged.AddDate(0, 0, 1),
+					},
+				},
+			},
+			{Handle: "hubot", Counts: map[string]int{"merged": 1}},
+		},
+		Failures: map[string]error{},
+	}
+}
+
+func TestMarkdownReportGolden(t *testing.T) {
+	showPRs = true
+	defer func() { showPRs = false }()
+	var out bytes.Buffer
+	renderReport(&out, "markdown", goldenResult(), []string{"merged", "open"})
+	checkGolden(t, "report.md", out.Bytes())
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"html/template"
+	"io"
+	"log"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func summaryHandles(summaries []pullpanda.Summary) []string {
+	handles := make([]string, len(summaries))
+	for i, summary := range summaries {
+		handles[i] = summary.Handle
+	}
+	return handles
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{"prLink": prLink, "labels": labelSuffix}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>PullPanda report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292f; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th, tfoot td { background: #f6f8fa; font-weight: 600; }
+td img { vertical-align: middle; border-radius: 50%; }
+a { color: #0969da; }
+</style>
+</head>
+<body>
+<table>
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr><td><img src="{{.Avatar}}" alt="" width="20" height="20"> {{index .Cells 0}}</td>{{range slice .Cells 1}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+{{- if .Footer}}
+<tfoot><tr>{{range .Footer}}<td>{{.}}</td>{{end}}</tr></tfoot>
+{{- end}}
+</table>
+{{- range .Notes}}
+<p><em>{{.}}</em></p>
+{{- end}}
+{{- if .PRs}}
+<h2>Detailed PRs</h2>
+{{- range .PRs}}
+<h3>{{.Label}}</h3>
+<ul>
+{{- range .PRs}}
+<li><a href="{{prLink .}}">{{.Title}}</a>{{labels .}}{{if .Repository}} {{.Repository}}#{{.Number}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// prLink is where a PR links to in the HTML and markdown reports: its page on
+// GitHub, or its API URL when the page isn't known.
+func prLink(pr pullpanda.PullRequest) string {
+	if pr.HTMLURL != "" {
+		return pr.HTMLURL
+	}
+	return pr.URL
+}
+
+// htmlPRs is one handle's section of the detailed PR list.
+type htmlPRs struct {
+	Label string
+	PRs   []pullpanda.PullRequest
+}
+
+type htmlRow struct {
+	Avatar template.URL
+	Cells  []string
+}
+
+// writeHTMLReport renders the summary as a self-contained HTML document with
+// each handle's avatar next to its row label, followed by the detailed PRs as
+// links when --show-prs is set. html/template escapes titles and names.
+func writeHTMLReport(w io.Writer, result pullpanda.RunResult, statuses []string) {
+	header, rows, footer := summaryTable(result, statuses)
+	handles := append(summaryHandles(shownSummaries(result.Summaries)), result.FailedHandles()...)
+
+	data := struct {
+		Header []string
+		Rows   []htmlRow
+		Footer []string
+		Notes  []string
+		PRs    []htmlPRs
+	}{Header: header, Notes: reportNotes(result)}
+	if !noFooter {
+		data.Footer = footer
+	}
+	for i, row := range rows {
+		data.Rows = append(data.Rows, htmlRow{
+			Avatar: template.URL(avatarURL(handles[i])),
+			Cells:  row,
+		})
+	}
+	if showPRs {
+		for _, summary := range shownSummaries(result.Summaries) {
+			if len(summary.PRs) > 0 {
+				data.PRs = append(data.PRs, htmlPRs{Label: summary.Label(), PRs: listedPRs(summary.PRs)})
+			}
+		}
+	}
+
+	if err := htmlReport.Execute(w, data); err != nil {
+		log.Fatalf("Error rendering HTML report: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// avatarServer answers user lookups with an avatar named after the handle.
+func avatarServer(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"avatar_url":"https://avatars.example.com/%s"}`, path.Base(r.URL.Path))
+	}))
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	t.Cleanup(func() {
+		srv.Close()
+		apiClient = nil
+	})
+}
+
+func TestHTMLReportGolden(t *testing.T) {
+	avatarServer(t)
+	showPRs = true
+	defer func() { showPRs = false }()
+
+	var out bytes.Buffer
+	writeHTMLReport(&out, goldenResult(), []string{"merged", "open"})
+	checkGolden(t, "report.html", out.Bytes())
+}
+
+func TestHTMLReportEscapesTitles(t *testing.T) {
+	avatarServer(t)
+	showPRs = true
+	defer func() { showPRs = false }()
+
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{{
+		Handle: "octocat",
+		Name:   "<b>Mona</b>",
+		Counts: map[string]int{"merged": 1},
+		PRs:    []pullpanda.PullRequest{{HTMLURL: "https://github.com/octo/api/pull/1", Title: `<script>alert("x")</script> & a < b`}},
+	}}}
+	var out bytes.Buffer
+	writeHTMLReport(&out, result, []string{"merged"})
+	html := out.String()
+
+	for _, raw := range []string{"<script>", "<b>Mona</b>", "a < b"} {
+		if strings.Contains(html, raw) {
+			t.Errorf("report contains unescaped %q:\n%s", raw, html)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;", "&lt;b&gt;Mona&lt;/b&gt;", "&amp; a &lt; b"} {
+		if !strings.Contains(html, escaped) {
+			t.Errorf("report lacks escaped %q:\n%s", escaped, html)
+		}
+	}
+}
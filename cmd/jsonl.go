@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// jsonlRecord is one line of --output jsonl: a PR with the handle it was
+// found for.
+type jsonlRecord struct {
+	Handle string `json:"handle"`
+	pullpanda.PullRequest
+}
+
+// jsonlStreamer returns a pullpanda.Client.OnPR callback writing each PR to w
+// as a line of JSON. Lines are written whole under a lock, so concurrent
+// handles never interleave.
+func jsonlStreamer(w io.Writer) func(string, pullpanda.PullRequest) {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(handle string, pr pullpanda.PullRequest) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := encoder.Encode(jsonlRecord{Handle: handle, PullRequest: redactPR(pr)}); err != nil {
+			log.Printf("Error writing JSON line for %s: %v\n", pr.URL, err)
+		}
+	}
+}
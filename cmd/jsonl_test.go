@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// TestJSONLStreamerWritesWholeLines streams PRs from many goroutines at once
+// and checks every line is a JSON object of its own.
+func TestJSONLStreamerWritesWholeLines(t *testing.T) {
+	var out bytes.Buffer
+	stream := jsonlStreamer(&out)
+	var wg sync.WaitGroup
+	for h := 0; h < 4; h++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 1; n <= 25; n++ {
+				stream(fmt.Sprintf("user%d", h), pullpanda.PullRequest{Number: n, Title: "fix \"quotes\"\nand newlines", Repository: "octo/api"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	scanner := bufio.NewScanner(&out)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var record struct {
+			Handle string `json:"handle"`
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d isn't JSON: %v\n%s", lines, err, scanner.Text())
+		}
+		if record.Handle == "" || record.Number == 0 || record.Title != "fix \"quotes\"\nand newlines" {
+			t.Errorf("line %d decoded to %+v", lines, record)
+		}
+	}
+	if lines != 100 {
+		t.Errorf("wrote %d lines, want 100", lines)
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var asciiMedals bool
+
+var leaderboardCmd = &cobra.Command{
+	Use:   "leaderboard",
+	Short: "Rank handles by their total contributions",
+	Long: `Leaderboard fetches PRs like the default command and ranks the handles by
+their total across all statuses, with medals for the top three. Equal totals
+share a rank, and the next rank skips accordingly (1, 1, 3).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := setupRun(cmd)
+		window, err := resolveDateRange()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		result := fetchAllPRs(config, window)
+		logFailures(result)
+
+		out, err := openOutput(window)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printLeaderboard(out, rankSummaries(result.Summaries))
+		if err := closeOutput(out); err != nil {
+			log.Fatal(err)
+		}
+		if summaryLine {
+			printSummaryLine(os.Stderr, result)
+		}
+		if code := exitCode(result, failOnEmpty); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+func init() {
+	leaderboardCmd.Flags().BoolVar(&asciiMedals, "ascii", false, "Show the medals as text instead of emoji")
+	rootCmd.AddCommand(leaderboardCmd)
+}
+
+// rankedSummary is a leaderboard row.
+type rankedSummary struct {
+	Rank    int
+	Total   int
+	Summary pullpanda.Summary
+}
+
+// rankSummaries sorts summaries by total, highest first, keeping config order
+// among equal totals, and gives equal totals the same rank.
+func rankSummaries(summaries []pullpanda.Summary) []rankedSummary {
+	ranked := make([]rankedSummary, len(summaries))
+	for i, total := range summaryTotals(summaries) {
+		ranked[i] = rankedSummary{Total: total, Summary: summaries[i]}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Total > ranked[j].Total
+	})
+	for i := range ranked {
+		if i > 0 && ranked[i].Total == ranked[i-1].Total {
+			ranked[i].Rank = ranked[i-1].Rank
+		} else {
+			ranked[i].Rank = i + 1
+		}
+	}
+	return ranked
+}
+
+var (
+	emojiMedals = []string{"🥇", "🥈", "🥉"}
+	textMedals  = []string{"gold", "silver", "bronze"}
+)
+
+// medal returns the medal for the top three ranks, or "" below them.
+func medal(rank int) string {
+	medals := emojiMedals
+	if asciiMedals {
+		medals = textMedals
+	}
+	if rank < 1 || rank > len(medals) {
+		return ""
+	}
+	return medals[rank-1]
+}
+
+func printLeaderboard(w io.Writer, ranked []rankedSummary) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Rank", "Handle", "Total", "Medal"})
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+	for _, r := range ranked {
+		table.Append([]string{strconv.Itoa(r.Rank), r.Summary.Label(), formatCount(r.Total), medal(r.Rank)})
+	}
+	table.Render()
+}
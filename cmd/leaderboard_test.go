@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestRankSummariesSharesRanksOnTies(t *testing.T) {
+	summaries := []pullpanda.Summary{
+		{Handle: "low", Counts: map[string]int{"merged": 1}},
+		{Handle: "tied-first", Counts: map[string]int{"merged": 3, "open": 2}},
+		{Handle: "top", Counts: map[string]int{"merged": 9}},
+		{Handle: "tied-second", Counts: map[string]int{"open": 5}},
+		{Handle: "none", Counts: map[string]int{}},
+	}
+	var got []string
+	var ranks, totals []int
+	for _, r := range rankSummaries(summaries) {
+		got = append(got, r.Summary.Handle)
+		ranks = append(ranks, r.Rank)
+		totals = append(totals, r.Total)
+	}
+	if want := []string{"top", "tied-first", "tied-second", "low", "none"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+	if want := []int{1, 2, 2, 4, 5}; !reflect.DeepEqual(ranks, want) {
+		t.Errorf("ranks = %v, want %v", ranks, want)
+	}
+	if want := []int{9, 5, 5, 1, 0}; !reflect.DeepEqual(totals, want) {
+		t.Errorf("totals = %v, want %v", totals, want)
+	}
+}
+
+func TestMedal(t *testing.T) {
+	defer func() { asciiMedals = false }()
+	tests := []struct {
+		rank  int
+		ascii bool
+		want  string
+	}{
+		{1, false, "🥇"},
+		{3, false, "🥉"},
+		{2, true, "silver"},
+		{4, false, ""},
+		{0, true, ""},
+	}
+	for _, tt := range tests {
+		asciiMedals = tt.ascii
+		if got := medal(tt.rank); got != tt.want {
+			t.Errorf("medal(%d) with --ascii=%v = %q, want %q", tt.rank, tt.ascii, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	loginClientID string
+	loginURL      string
+	loginScopes   string
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Get a GitHub token through the OAuth device flow and store it",
+	Long: `Login asks GitHub for a device code, prints the code and the page to enter
+it on, and waits until it has been approved. The token is stored in the
+credentials file, which later runs use when no other token is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if loginClientID == "" {
+			log.Fatal("--client-id is required; register an OAuth app with the device flow enabled to get one")
+		}
+		var err error
+		if proxyURL, err = parseProxy(proxy); err != nil {
+			log.Fatal(err)
+		}
+		if tlsConfig, err = buildTLSConfig(caCert, insecure); err != nil {
+			log.Fatal(err)
+		}
+		path, err := credentialsPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		flow := deviceFlow{HTTPClient: newHTTPClient(), BaseURL: loginURL, ClientID: loginClientID}
+		code, err := flow.requestCode(context.Background(), loginScopes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+		accessToken, err := flow.pollToken(context.Background(), code)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := storeCredentials(path, accessToken); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Logged in, token stored in %s\n", path)
+	},
+}
+
+func init() {
+	loginCmd.Flags().StringVar(&loginClientID, "client-id", os.Getenv("PULLPANDA_CLIENT_ID"), "Client ID of the OAuth app to log in with (default $PULLPANDA_CLIENT_ID)")
+	loginCmd.Flags().StringVar(&loginURL, "login-url", "https://github.com", "GitHub web URL serving the device flow, e.g. https://github.example.com for GitHub Enterprise")
+	loginCmd.Flags().StringVar(&loginScopes, "scopes", "repo read:org", "OAuth scopes to request, space separated")
+	rootCmd.AddCommand(loginCmd)
+}
+
+// credentialsPath is the file login stores the token in, in the user's
+// config directory.
+func credentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding the config directory: %w", err)
+	}
+	return filepath.Join(dir, "pullpanda", "credentials"), nil
+}
+
+// storeCredentials writes token to path, readable by the user only.
+func storeCredentials(path, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error creating credentials directory: %w", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return fmt.Errorf("error writing credentials: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0o600)
+}
+
+// storedToken returns the token stored by login, or "" when there is none.
+func storedToken() string {
+	path, err := credentialsPath()
+	if err != nil {
+		return ""
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// deviceFlow runs GitHub's OAuth device authorization flow.
+type deviceFlow struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	ClientID   string
+}
+
+// deviceCode is GitHub's answer to a device code request. Interval is the
+// minimum number of seconds between polls.
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+func (f deviceFlow) requestCode(ctx context.Context, scopes string) (deviceCode, error) {
+	var code deviceCode
+	err := f.post(ctx, "/login/device/code", url.Values{"client_id": {f.ClientID}, "scope": {scopes}}, &code)
+	if err != nil {
+		return code, fmt.Errorf("error requesting device code: %w", err)
+	}
+	if code.DeviceCode == "" {
+		return code, errors.New("error requesting device code: no device_code in response")
+	}
+	return code, nil
+}
+
+// devicePollUnit is the unit of the poll intervals, which GitHub gives in
+// seconds.
+var devicePollUnit = time.Second
+
+// pollToken polls until the user approves code, honoring the interval and
+// GitHub's slow_down answers, and fails once the code expires or is denied.
+func (f deviceFlow) pollToken(ctx context.Context, code deviceCode) (string, error) {
+	interval := time.Duration(code.Interval) * devicePollUnit
+	if interval <= 0 {
+		interval = 5 * devicePollUnit
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * devicePollUnit)
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+
+		var answer struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Interval    int    `json:"interval"`
+		}
+		err := f.post(ctx, "/login/oauth/access_token", url.Values{
+			"client_id":   {f.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &answer)
+		if err != nil {
+			return "", fmt.Errorf("error polling for the token: %w", err)
+		}
+		switch answer.Error {
+		case "":
+			if answer.AccessToken == "" {
+				return "", errors.New("error polling for the token: no access_token in response")
+			}
+			return answer.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			if answer.Interval > 0 {
+				interval = time.Duration(answer.Interval) * devicePollUnit
+			} else {
+				interval += 5 * devicePollUnit
+			}
+		case "expired_token":
+			return "", errors.New("the code expired before it was entered; run login again")
+		case "access_denied":
+			return "", errors.New("the login was denied")
+		default:
+			return "", fmt.Errorf("error polling for the token: %s", answer.Error)
+		}
+		if code.ExpiresIn > 0 && time.Now().After(deadline) {
+			return "", errors.New("the code expired before it was entered; run login again")
+		}
+	}
+}
+
+// post sends form to a device flow endpoint and decodes the JSON answer.
+func (f deviceFlow) post(ctx context.Context, path string, form url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(f.BaseURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
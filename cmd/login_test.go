@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deviceFlowServer serves the device code endpoint and answers the token
+// polls with answers in turn, recording them.
+func deviceFlowServer(t *testing.T, answers ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var polls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Accept") != "application/json" {
+			t.Errorf("%s %s with Accept %q, want a POST for JSON", r.Method, r.URL.Path, r.Header.Get("Accept"))
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != "test-client" {
+			t.Errorf("form %v, err %v, want the client ID", r.PostForm, err)
+		}
+		switch r.URL.Path {
+		case "/login/device/code":
+			if r.PostForm.Get("scope") != "repo read:org" {
+				t.Errorf("scope = %q", r.PostForm.Get("scope"))
+			}
+			fmt.Fprint(w, `{"device_code":"dev-123","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":5}`)
+		case "/login/oauth/access_token":
+			if r.PostForm.Get("device_code") != "dev-123" || r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" {
+				t.Errorf("poll form %v", r.PostForm)
+			}
+			answer := answers[min(len(polls), len(answers)-1)]
+			polls = append(polls, answer)
+			fmt.Fprint(w, answer)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &polls
+}
+
+func TestDeviceFlow(t *testing.T) {
+	devicePollUnit = time.Millisecond
+	defer func() { devicePollUnit = time.Second }()
+	tests := []struct {
+		name      string
+		answers   []string
+		wantToken string
+		wantErr   string
+		wantPolls int
+	}{
+		{
+			name:      "approved after waiting",
+			answers:   []string{`{"error":"authorization_pending"}`, `{"error":"slow_down","interval":10}`, `{"access_token":"gho_token","token_type":"bearer"}`},
+			wantToken: "gho_token",
+			wantPolls: 3,
+		},
+		{
+			name:      "denied",
+			answers:   []string{`{"error":"authorization_pending"}`, `{"error":"access_denied"}`},
+			wantErr:   "the login was denied",
+			wantPolls: 2,
+		},
+		{
+			name:      "expired",
+			answers:   []string{`{"error":"expired_token"}`},
+			wantErr:   "the code expired",
+			wantPolls: 1,
+		},
+	}
+	for _, tt := range tests {
+		srv, polls := deviceFlowServer(t, tt.answers...)
+		flow := deviceFlow{HTTPClient: srv.Client(), BaseURL: srv.URL + "/", ClientID: "test-client"}
+		code, err := flow.requestCode(context.Background(), "repo read:org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := deviceCode{DeviceCode: "dev-123", UserCode: "ABCD-1234", VerificationURI: "https://github.com/login/device", ExpiresIn: 900, Interval: 5}
+		if !reflect.DeepEqual(code, want) {
+			t.Errorf("code = %+v, want %+v", code, want)
+		}
+
+		token, err := flow.pollToken(context.Background(), code)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+		} else if err != nil || token != tt.wantToken {
+			t.Errorf("%s: token = %q, %v, want %q", tt.name, token, err, tt.wantToken)
+		}
+		if len(*polls) != tt.wantPolls {
+			t.Errorf("%s: polled %d times, want %d", tt.name, len(*polls), tt.wantPolls)
+		}
+	}
+}
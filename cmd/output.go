@@ -0,0 +1,844 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// estimateNote labels reports produced with --estimate.
+const estimateNote = "Counts are estimates from search totals; GitHub caps search results at 1000, so large totals may be approximate."
+
+// reportNotes returns the caveats printed below the summary table.
+func reportNotes(result pullpanda.RunResult) []string {
+	var notes []string
+	for _, handle := range result.FailedHandles() {
+		notes = append(notes, fmt.Sprintf("%s*: fetching failed: %v", handle, result.Failures[handle]))
+	}
+	if len(result.Unfinished) > 0 {
+		notes = append(notes, fmt.Sprintf("Run interrupted before these handles were fetched: %s.", strings.Join(result.Unfinished, ", ")))
+	}
+	if estimate {
+		notes = append(notes, estimateNote)
+	}
+	var capped []string
+	for _, summary := range result.Summaries {
+		if summary.Capped {
+			capped = append(capped, summary.Label())
+		}
+	}
+	if len(capped) > 0 {
+		notes = append(notes, fmt.Sprintf("Counts are estimates for: %s. A search matched more than the 1000 PRs GitHub lists, and only the listed ones could be filtered.", strings.Join(capped, ", ")))
+	}
+	if len(result.SkippedScopes) > 0 {
+		notes = append(notes, fmt.Sprintf("Skipped scopes that don't exist or can't be searched: %s.", strings.Join(result.SkippedScopes, ", ")))
+	}
+	aboveMin := aboveMinPRs(result.Summaries)
+	if hidden := len(result.Summaries) - len(aboveMin); hidden > 0 {
+		note := fmt.Sprintf("%d handle(s) with fewer than %d PRs hidden", hidden, minPRs)
+		if minPRsInTotals {
+			note += "; they are still included in the totals."
+		} else {
+			note += " and left out of the totals."
+		}
+		notes = append(notes, note)
+	}
+	if hidden := len(aboveMin) - len(topSummaries(aboveMin)); hidden > 0 {
+		note := fmt.Sprintf("%d handle(s) outside the top %d hidden", hidden, top)
+		if topInTotals {
+			note += "; they are still included in the totals."
+		} else {
+			note += " and left out of the totals."
+		}
+		notes = append(notes, note)
+	}
+
+	var truncated []string
+	for _, summary := range result.Summaries {
+		if summary.Truncated {
+			truncated = append(truncated, summary.Label())
+		}
+	}
+	if len(truncated) > 0 && showPRs {
+		notes = append(notes, fmt.Sprintf("PR lists truncated for: %s. Counts still include every matching PR.", strings.Join(truncated, ", ")))
+	}
+	return notes
+}
+
+var outputFormats = []string{"table", "markdown", "html", "jsonl", "prometheus"}
+
+// outputPlaceholder matches the {name} placeholders of --output-file.
+var outputPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// validateOutputFile checks that --output-file only uses known placeholders.
+func validateOutputFile(name string) error {
+	for _, placeholder := range outputPlaceholder.FindAllString(name, -1) {
+		if placeholder != "{date}" {
+			return fmt.Errorf("unknown placeholder %s in --output-file, only {date} is supported", placeholder)
+		}
+	}
+	return nil
+}
+
+// outputPath expands {date} in --output-file to the end of window, or today
+// when the range is open-ended.
+func outputPath(window pullpanda.DateRange) string {
+	date := window.End
+	if date == "" {
+		date = now().Format("2006-01-02")
+	}
+	return strings.ReplaceAll(outputFile, "{date}", date)
+}
+
+// openOutput returns where the report for window is rendered: --output-file,
+// truncated and with its parent directories created, or stdout when it's
+// unset.
+func openOutput(window pullpanda.DateRange) (*os.File, error) {
+	if outputFile == "" {
+		return os.Stdout, nil
+	}
+	path := outputPath(window)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file: %w", err)
+	}
+	return f, nil
+}
+
+// closeOutput closes the file returned by openOutput, leaving stdout open.
+func closeOutput(f *os.File) error {
+	if f == os.Stdout {
+		return nil
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+	return nil
+}
+
+func validateOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected one of: %s", format, strings.Join(outputFormats, ", "))
+}
+
+// renderReport writes the summary, and the detailed PR list when --show-prs
+// is set, in the requested output format.
+func renderReport(w io.Writer, format string, result pullpanda.RunResult, statuses []string) {
+	summaries := shownSummaries(result.Summaries)
+	switch format {
+	case "markdown":
+		writeMarkdownSummary(w, result, statuses)
+		for _, note := range reportNotes(result) {
+			fmt.Fprintf(w, "\n_%s_\n", markdownCellEscaper.Replace(note))
+		}
+		if showPRs {
+			writeMarkdownPRs(w, summaries)
+		}
+	case "html":
+		writeHTMLReport(w, result, statuses)
+	case "jsonl":
+		// The PRs were already streamed while fetching.
+	case "prometheus":
+		writePrometheus(w, result, statuses)
+	default:
+		printSummaryTable(w, result, statuses)
+		for _, note := range reportNotes(result) {
+			fmt.Fprintln(w, note)
+		}
+		if showPRs {
+			printDetailedPRs(w, summaries)
+		}
+	}
+}
+
+// summaryTable builds the header, body rows and totals footer shared by all
+// summary renderers. Failed handles get a row of dashes after the successful
+// ones, marked with an asterisk that reportNotes explains.
+func summaryTable(result pullpanda.RunResult, statuses []string) ([]string, [][]string, []string) {
+	summaries := result.Summaries
+	columns := summaryColumns(totaledSummaries(summaries), statuses)
+	header := append([]string{"Handle"}, statuses...)
+	header = append(header, "Total")
+	for _, column := range columns {
+		header = append(header, column.Header)
+	}
+
+	var rows [][]string
+	for _, summary := range shownSummaries(summaries) {
+		row := []string{summary.Label()}
+		total := 0
+		for _, status := range statuses {
+			count := summary.Counts[status]
+			row = append(row, formatCount(count))
+			total += count
+		}
+		row = append(row, formatCount(total))
+		for _, column := range columns {
+			row = append(row, column.Cell(summary))
+		}
+		rows = append(rows, row)
+	}
+	for _, handle := range result.FailedHandles() {
+		row := []string{handle + "*"}
+		for range header[1:] {
+			row = append(row, "-")
+		}
+		rows = append(rows, row)
+	}
+
+	summaries = totaledSummaries(summaries)
+	totalCounts := make(map[string]int)
+	for _, summary := range summaries {
+		for _, status := range statuses {
+			totalCounts[status] += summary.Counts[status]
+		}
+	}
+
+	footer := []string{"Total"}
+	grandTotal := 0
+	for _, status := range statuses {
+		total := totalCounts[status]
+		footer = append(footer, formatCount(total))
+		grandTotal += total
+	}
+	footer = append(footer, formatCount(grandTotal))
+	for _, column := range columns {
+		footer = append(footer, column.Footer(summaries))
+	}
+
+	return header, rows, footer
+}
+
+// shownSummaries drops the summaries whose total is below --min-prs, keeping
+// only the --top ones of the rest.
+func shownSummaries(summaries []pullpanda.Summary) []pullpanda.Summary {
+	return topSummaries(aboveMinPRs(summaries))
+}
+
+// aboveMinPRs drops the summaries whose total is below --min-prs.
+func aboveMinPRs(summaries []pullpanda.Summary) []pullpanda.Summary {
+	if minPRs <= 0 {
+		return summaries
+	}
+	var shown []pullpanda.Summary
+	for i, total := range summaryTotals(summaries) {
+		if total >= minPRs {
+			shown = append(shown, summaries[i])
+		}
+	}
+	return shown
+}
+
+// topSummaries keeps the --top summaries with the highest totals, ties going
+// to the earlier ones, in the order they were given, so --sort still orders
+// them.
+func topSummaries(summaries []pullpanda.Summary) []pullpanda.Summary {
+	if top <= 0 || len(summaries) <= top {
+		return summaries
+	}
+	totals := summaryTotals(summaries)
+	order := make([]int, len(summaries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return totals[order[i]] > totals[order[j]] })
+	kept := make(map[int]bool, top)
+	for _, i := range order[:top] {
+		kept[i] = true
+	}
+	var shown []pullpanda.Summary
+	for i, summary := range summaries {
+		if kept[i] {
+			shown = append(shown, summary)
+		}
+	}
+	return shown
+}
+
+// summaryColumn is an optional column shown after the totals.
+type summaryColumn struct {
+	Header string
+	Cell   func(pullpanda.Summary) string
+	Footer func([]pullpanda.Summary) string
+}
+
+// totaledSummaries returns the summaries counted in the totals. Rows hidden
+// by --min-prs or --top still count toward them unless --min-prs-in-totals or
+// --top-in-totals is false.
+func totaledSummaries(summaries []pullpanda.Summary) []pullpanda.Summary {
+	if !minPRsInTotals {
+		summaries = aboveMinPRs(summaries)
+	}
+	if !topInTotals {
+		summaries = topSummaries(summaries)
+	}
+	return summaries
+}
+
+// summaryColumns returns the optional columns that apply to this run.
+// totaled are the summaries counted in the totals, which shares refer to.
+func summaryColumns(totaled []pullpanda.Summary, statuses []string) []summaryColumn {
+	teamTotal := grandTotal(totaled)
+	columns := []summaryColumn{{
+		Header: "Share",
+		Cell: func(s pullpanda.Summary) string {
+			return formatShare(grandTotal([]pullpanda.Summary{s}), teamTotal)
+		},
+		Footer: func([]pullpanda.Summary) string { return formatShare(teamTotal, teamTotal) },
+	}}
+	if includeIssues {
+		columns = append(columns, summaryColumn{
+			Header: "Issues",
+			Cell:   func(s pullpanda.Summary) string { return formatCount(s.Issues) },
+			Footer: func(summaries []pullpanda.Summary) string {
+				total := 0
+				for _, s := range summaries {
+					total += s.Issues
+				}
+				return formatCount(total)
+			},
+		})
+	}
+	if includeReviewComments {
+		columns = append(columns, summaryColumn{
+			Header: "Review comments",
+			Cell:   func(s pullpanda.Summary) string { return formatCount(s.ReviewComments) },
+			Footer: func(summaries []pullpanda.Summary) string {
+				total := 0
+				for _, s := range summaries {
+					total += s.ReviewComments
+				}
+				return formatCount(total)
+			},
+		})
+	}
+	if includeCommits {
+		columns = append(columns, summaryColumn{
+			Header: "Commits",
+			Cell:   func(s pullpanda.Summary) string { return formatCount(s.Commits) },
+			Footer: func(summaries []pullpanda.Summary) string {
+				total := 0
+				for _, s := range summaries {
+					total += s.Commits
+				}
+				return formatCount(total)
+			},
+		})
+	}
+	if includeCoAuthored {
+		columns = append(columns, summaryColumn{
+			Header: "Co-authored",
+			Cell:   func(s pullpanda.Summary) string { return formatCount(s.CoAuthored) },
+			Footer: func(summaries []pullpanda.Summary) string {
+				total := 0
+				for _, s := range summaries {
+					total += s.CoAuthored
+				}
+				return formatCount(total)
+			},
+		})
+	}
+	if hasMergeRate(statuses) {
+		columns = append(columns, summaryColumn{
+			Header: "Merge rate",
+			Cell: func(s pullpanda.Summary) string {
+				return mergeRate(s.Counts)
+			},
+			Footer: func(summaries []pullpanda.Summary) string {
+				totals := make(map[string]int)
+				for _, s := range summaries {
+					for status, count := range s.Counts {
+						totals[status] += count
+					}
+				}
+				return mergeRate(totals)
+			},
+		})
+	}
+	if showTurnaround {
+		columns = append(columns, summaryColumn{
+			Header: "Avg turnaround",
+			Cell: func(s pullpanda.Summary) string {
+				return formatTurnaround(averageTurnaround([]pullpanda.Summary{s}))
+			},
+			Footer: func(summaries []pullpanda.Summary) string {
+				return formatTurnaround(averageTurnaround(summaries))
+			},
+		})
+	}
+	if showSparkline {
+		columns = append(columns, summaryColumn{
+			Header: "Activity",
+			Cell:   func(s pullpanda.Summary) string { return sparkline(s.Weekly) },
+			Footer: func(summaries []pullpanda.Summary) string {
+				var weekly []int
+				for _, s := range summaries {
+					for i, count := range s.Weekly {
+						if i == len(weekly) {
+							weekly = append(weekly, 0)
+						}
+						weekly[i] += count
+					}
+				}
+				return sparkline(weekly)
+			},
+		})
+	}
+	if !countOnly && !useGraphQL {
+		columns = append(columns,
+			summaryColumn{
+				Header: "Repos",
+				Cell:   func(s pullpanda.Summary) string { return formatCount(len(s.Repos)) },
+				Footer: func(summaries []pullpanda.Summary) string {
+					repos := make(map[string]bool)
+					for _, s := range summaries {
+						for _, repo := range s.Repos {
+							repos[repo] = true
+						}
+					}
+					return formatCount(len(repos))
+				},
+			},
+			summaryColumn{
+				Header: "First PR",
+				Cell:   func(s pullpanda.Summary) string { return formatDate(s.FirstPR) },
+				Footer: func(summaries []pullpanda.Summary) string {
+					var first time.Time
+					for _, s := range summaries {
+						if first.IsZero() || (!s.FirstPR.IsZero() && s.FirstPR.Before(first)) {
+							first = s.FirstPR
+						}
+					}
+					return formatDate(first)
+				},
+			},
+			summaryColumn{
+				Header: "Last PR",
+				Cell:   func(s pullpanda.Summary) string { return formatDate(s.LastPR) },
+				Footer: func(summaries []pullpanda.Summary) string {
+					var last time.Time
+					for _, s := range summaries {
+						if s.LastPR.After(last) {
+							last = s.LastPR
+						}
+					}
+					return formatDate(last)
+				},
+			})
+	}
+	return columns
+}
+
+// formatCount formats a count for the tables, with thousands separators when
+// --humanize is set, e.g. 12,345, and zero as --empty-as asks.
+func formatCount(n int) string {
+	if n == 0 {
+		return emptyCell()
+	}
+	s := strconv.Itoa(n)
+	if !humanize {
+		return s
+	}
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+// emptyCell returns the table cell of a zero count: "0" for --empty-as zero,
+// "-" for dash, and the --empty-as value itself otherwise.
+func emptyCell() string {
+	switch emptyAs {
+	case "", "zero":
+		return "0"
+	case "dash":
+		return "-"
+	}
+	return emptyAs
+}
+
+// formatShare formats total as a percentage of teamTotal, or "-" when the
+// team has no PRs.
+func formatShare(total, teamTotal int) string {
+	if teamTotal == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(total)/float64(teamTotal)*100)
+}
+
+// formatDate formats t as YYYY-MM-DD, or "-" when it is zero.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+// hasMergeRate reports whether statuses include merged PRs and something to
+// compare them against.
+func hasMergeRate(statuses []string) bool {
+	var merged, other bool
+	for _, status := range statuses {
+		switch status {
+		case "merged":
+			merged = true
+		case "open", "closed":
+			other = true
+		}
+	}
+	return merged && other
+}
+
+// mergeRate formats merged PRs as a percentage of all authored PRs, open,
+// closed and merged, or "-" when there are none. Closed PRs are queried
+// without the merged ones whenever merged is queried too, so they don't
+// overlap.
+func mergeRate(counts map[string]int) string {
+	authored := counts["open"] + counts["closed"] + counts["merged"]
+	if authored == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(counts["merged"])/float64(authored)*100)
+}
+
+// averageTurnaround returns the mean time from creation to merge of the
+// merged PRs of summaries, and 0 when there are none.
+func averageTurnaround(summaries []pullpanda.Summary) time.Duration {
+	var total time.Duration
+	merged := 0
+	for _, s := range summaries {
+		for _, pr := range s.PRs {
+			if pr.Merged && pr.MergedAt != nil && !pr.CreatedAt.IsZero() {
+				total += pr.MergedAt.Sub(pr.CreatedAt)
+				merged++
+			}
+		}
+	}
+	if merged == 0 {
+		return 0
+	}
+	return total / time.Duration(merged)
+}
+
+// formatTurnaround formats d as days and hours, e.g. "3d4h", or minutes
+// under an hour, and as "-" when it's 0.
+func formatTurnaround(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	days := int(d.Hours()) / 24
+	return fmt.Sprintf("%dd%dh", days, int(d.Hours())-days*24)
+}
+
+// summaryTotals returns the total across all statuses for each summary.
+func summaryTotals(summaries []pullpanda.Summary) []int {
+	totals := make([]int, len(summaries))
+	for i, summary := range summaries {
+		for _, count := range summary.Counts {
+			totals[i] += count
+		}
+	}
+	return totals
+}
+
+func printSummaryTable(w io.Writer, result pullpanda.RunResult, statuses []string) {
+	header, rows, footer := summaryTable(result, statuses)
+
+	// Format the header and the footer label like tablewriter would, while
+	// keeping the footer cells as they are, e.g. "3d4h" rather than "3D4H"
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	titled := make([]string, len(header))
+	for i, h := range header {
+		titled[i] = tablewriter.Title(h)
+	}
+	table.SetHeader(titled)
+	footer = append([]string{tablewriter.Title(footer[0])}, footer[1:]...)
+
+	// Align explicitly: tablewriter only right-aligns cells it detects as
+	// numbers, which misses percentages and colored cells
+	alignment := []int{tablewriter.ALIGN_LEFT}
+	for range header[1:] {
+		alignment = append(alignment, tablewriter.ALIGN_RIGHT)
+	}
+	table.SetColumnAlignment(alignment)
+
+	if useColor(colorMode) {
+		headerColors := make([]tablewriter.Colors, len(header))
+		for i := range headerColors {
+			headerColors[i] = tablewriter.Colors{tablewriter.Bold}
+		}
+		table.SetHeaderColor(headerColors...)
+		appendColoredRows(table, rows, summaryTotals(shownSummaries(result.Summaries)))
+	} else {
+		table.AppendBulk(rows)
+	}
+	if !noFooter {
+		table.SetFooter(footer)
+		table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
+	}
+	if !noMerge {
+		table.SetAutoMergeCellsByColumnIndex([]int{0})
+	}
+
+	table.Render()
+}
+
+// prSortKeys are the accepted --sort-prs values.
+var prSortKeys = []string{"date", "title", "repo"}
+
+func validatePRSortKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	for _, k := range prSortKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --sort-prs key %q, expected one of: %s", key, strings.Join(prSortKeys, ", "))
+}
+
+// sortedPRs returns prs ordered by --sort-prs, by date when it is unset:
+// newest first, or oldest first with --oldest-first. Ties keep their fetch
+// order.
+func sortedPRs(prs []pullpanda.PullRequest) []pullpanda.PullRequest {
+	sorted := append([]pullpanda.PullRequest{}, prs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		switch sortPRs {
+		case "", "date":
+			a, _ := prDate(sorted[i])
+			b, _ := prDate(sorted[j])
+			if oldestFirst {
+				return a.Before(b)
+			}
+			return a.After(b)
+		case "title":
+			return strings.ToLower(sorted[i].Title) < strings.ToLower(sorted[j].Title)
+		default:
+			return sorted[i].Repository < sorted[j].Repository
+		}
+	})
+	return sorted
+}
+
+// summarySortKeys are the accepted --sort values.
+var summarySortKeys = []string{"config", "handle", "total"}
+
+func validateSummarySortKey(key string) error {
+	for _, k := range summarySortKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --sort key %q, expected one of: %s", key, strings.Join(summarySortKeys, ", "))
+}
+
+// summaryOrder returns the indexes of summaries in --sort order: the order
+// of the handles in config by default, by handle case-insensitively, or by
+// total with the most PRs first. Ties keep config order. The order is
+// computed here rather than relied on from the fetch, so it's the same
+// whichever handle finished first.
+func summaryOrder(summaries []pullpanda.Summary, config pullpanda.Config) []int {
+	position := make(map[string]int)
+	for i, handle := range config.Handles {
+		if _, ok := position[handle.Handle]; !ok {
+			position[handle.Handle] = i
+		}
+	}
+	totals := summaryTotals(summaries)
+	order := make([]int, len(summaries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		switch sortSummaries {
+		case "handle":
+			if x, y := strings.ToLower(summaries[a].Handle), strings.ToLower(summaries[b].Handle); x != y {
+				return x < y
+			}
+		case "total":
+			if totals[a] != totals[b] {
+				return totals[a] > totals[b]
+			}
+		}
+		return position[summaries[a].Handle] < position[summaries[b].Handle]
+	})
+	return order
+}
+
+// sortedSummaries returns summaries in summaryOrder.
+func sortedSummaries(summaries []pullpanda.Summary, config pullpanda.Config) []pullpanda.Summary {
+	sorted := make([]pullpanda.Summary, len(summaries))
+	for i, index := range summaryOrder(summaries, config) {
+		sorted[i] = summaries[index]
+	}
+	return sorted
+}
+
+// prDateFields are the accepted --date-field values.
+var prDateFields = []string{"created", "merged", "closed"}
+
+func validatePRDateField(field string) error {
+	if field == "" {
+		return nil
+	}
+	for _, f := range prDateFields {
+		if f == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --date-field %q, expected one of: %s", field, strings.Join(prDateFields, ", "))
+}
+
+// prDate returns the date shown and sorted on for pr in the detailed list,
+// and which one it is: the --date-field date, or when it is unset the merge
+// date when pr was merged and its creation date otherwise. It is zero when
+// pr has no such date, e.g. the merge date of an open PR.
+func prDate(pr pullpanda.PullRequest) (time.Time, string) {
+	var date *time.Time
+	switch prDateField {
+	case "created":
+		return pr.CreatedAt, "created"
+	case "merged":
+		date = pr.MergedAt
+	case "closed":
+		date = pr.ClosedAt
+	default:
+		if pr.Merged && pr.MergedAt != nil {
+			return *pr.MergedAt, "merged"
+		}
+		return pr.CreatedAt, "created"
+	}
+	if date == nil {
+		return time.Time{}, prDateField
+	}
+	return *date, prDateField
+}
+
+// dateSuffix formats the date of pr for the detailed list, e.g.
+// " (merged 2024-03-01)", or returns "" when it is unknown.
+func dateSuffix(pr pullpanda.PullRequest) string {
+	date, kind := prDate(pr)
+	if date.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (%s %s)", kind, date.Format("2006-01-02"))
+}
+
+// labelSuffix lists pr's labels after its title with --show-labels, e.g.
+// " (bug, docs)", or returns "".
+func labelSuffix(pr pullpanda.PullRequest) string {
+	if !showLabels || len(pr.Labels) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(pr.Labels, ", ") + ")"
+}
+
+func printDetailedPRs(w io.Writer, summaries []pullpanda.Summary) {
+	fmt.Fprintln(w, "\nDetailed PRs:")
+	for _, summary := range summaries {
+		for _, pr := range listedPRs(summary.PRs) {
+			fmt.Fprintf(w, "- [%s]%s %s%s\n", pr.Title, labelSuffix(pr), pr.URL, dateSuffix(pr))
+		}
+	}
+}
+
+// writeMarkdownSummary renders the summary as a GitHub-flavored markdown table
+// with the totals as its last row.
+func writeMarkdownSummary(w io.Writer, result pullpanda.RunResult, statuses []string) {
+	header, rows, footer := summaryTable(result, statuses)
+
+	writeMarkdownRow(w, header)
+	separator := make([]string, len(header))
+	separator[0] = "---"
+	for i := 1; i < len(separator); i++ {
+		separator[i] = "---:"
+	}
+	writeMarkdownRow(w, separator)
+	for _, row := range rows {
+		writeMarkdownRow(w, row)
+	}
+	if noFooter {
+		return
+	}
+	cells := markdownCells(footer)
+	cells[0] = "**" + cells[0] + "**"
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
+
+func writeMarkdownPRs(w io.Writer, summaries []pullpanda.Summary) {
+	fmt.Fprintln(w, "\n### Detailed PRs")
+	fmt.Fprintln(w)
+	for _, summary := range summaries {
+		for _, pr := range listedPRs(summary.PRs) {
+			fmt.Fprintf(w, "- [%s](%s)%s%s\n", markdownEscaper.Replace(pr.Title), prLink(pr), markdownCellEscaper.Replace(labelSuffix(pr)), dateSuffix(pr))
+		}
+	}
+}
+
+// markdownEscaper escapes characters that would break a link label.
+var markdownEscaper = strings.NewReplacer("[", "\\[", "]", "\\]")
+
+// markdownCellEscaper escapes characters with a meaning inside table cells.
+var markdownCellEscaper = strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_")
+
+func markdownCells(cells []string) []string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = markdownCellEscaper.Replace(cell)
+	}
+	return escaped
+}
+
+func writeMarkdownRow(w io.Writer, cells []string) {
+	fmt.Fprintf(w, "| %s |\n", strings.Join(markdownCells(cells), " | "))
+}
+
+// writeStepSummary appends the markdown report to the file GitHub Actions
+// exposes through GITHUB_STEP_SUMMARY. Outside of Actions it is a no-op.
+func writeStepSummary(result pullpanda.RunResult, statuses []string) {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		warnf("--step-summary set but GITHUB_STEP_SUMMARY is not defined, skipping step summary")
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		warnf("could not open step summary file: %v", err)
+		return
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, "## PullPanda contributions")
+	fmt.Fprintln(file)
+	renderReport(file, "markdown", result, statuses)
+	fmt.Fprintln(file)
+}
@@ -0,0 +1,563 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestEmptyAsRendersZeroCells(t *testing.T) {
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 0, "open": 2}},
+	}}
+	emptyAs = "dash"
+	defer func() { emptyAs = "" }()
+
+	var markdown bytes.Buffer
+	renderReport(&markdown, "markdown", result, []string{"merged", "open"})
+	if !strings.Contains(markdown.String(), "| octocat | - | 2 | 2 |") {
+		t.Errorf("zero count isn't rendered as a dash:\n%s", markdown.String())
+	}
+
+	var metrics bytes.Buffer
+	renderReport(&metrics, "prometheus", result, []string{"merged", "open"})
+	if !strings.Contains(metrics.String(), `pullpanda_prs_total{handle="octocat",status="merged"} 0`) {
+		t.Errorf("prometheus output lost the real zero:\n%s", metrics.String())
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n        int
+		humanize bool
+		emptyAs  string
+		want     string
+	}{
+		{0, false, "", "0"},
+		{0, false, "zero", "0"},
+		{0, false, "dash", "-"},
+		{0, false, "·", "·"},
+		{7, false, "dash", "7"},
+		{12345, true, "", "12,345"},
+		{-1234, true, "", "-1,234"},
+	}
+	for _, tt := range tests {
+		humanize, emptyAs = tt.humanize, tt.emptyAs
+		if got := formatCount(tt.n); got != tt.want {
+			t.Errorf("formatCount(%d) with humanize=%v, empty-as %q = %q, want %q", tt.n, tt.humanize, tt.emptyAs, got, tt.want)
+		}
+	}
+	humanize, emptyAs = false, ""
+}
+
+// TestSortedSummariesDeterministic fetches many handles at once from a server
+// answering after random delays, so they finish in a different order every
+// run, and checks every --sort order comes out the same each time.
+func TestSortedSummariesDeterministic(t *testing.T) {
+	author := regexp.MustCompile(`(?i)author:user(\d+)`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		match := author.FindStringSubmatch(r.URL.Query().Get("q"))
+		if match == nil {
+			http.Error(w, `{"message":"unexpected query"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		// Few distinct totals, so --sort total has ties to break
+		n, _ := strconv.Atoi(match[1])
+		fmt.Fprintf(w, `{"total_count":%d,"items":[]}`, n%3)
+	}))
+	defer srv.Close()
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	apiClient.CountOnly = true
+	defer func() { apiClient = nil }()
+
+	var config pullpanda.Config
+	for i := 0; i < 50; i++ {
+		// Alternate the case, so --sort handle differs from config order
+		handle := fmt.Sprintf("user%d", (i*7)%50)
+		if i%2 == 1 {
+			handle = strings.ToUpper(handle)
+		}
+		config.Handles = append(config.Handles, pullpanda.Handle{Handle: handle})
+	}
+	config.Statuses = []string{"merged"}
+
+	for _, key := range summarySortKeys {
+		t.Run(key, func(t *testing.T) {
+			sortSummaries = key
+			defer func() { sortSummaries = "config" }()
+
+			var first []string
+			for run := 0; run < 5; run++ {
+				result := fetchAllPRs(config, pullpanda.DateRange{})
+				var order []string
+				for _, summary := range sortedSummaries(result.Summaries, config) {
+					order = append(order, summary.Handle)
+				}
+				if len(order) != len(config.Handles) {
+					t.Fatalf("got %d summaries, want %d", len(order), len(config.Handles))
+				}
+				if run == 0 {
+					first = order
+				} else if !reflect.DeepEqual(order, first) {
+					t.Fatalf("run %d order = %q, want %q", run, order, first)
+				}
+			}
+			if key == "config" {
+				for i, handle := range config.Handles {
+					if first[i] != handle.Handle {
+						t.Fatalf("config order = %q, want the handles as configured", first)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReportNotesLabelEstimates(t *testing.T) {
+	exact := pullpanda.RunResult{Summaries: []pullpanda.Summary{{Handle: "octocat", Counts: map[string]int{"merged": 3}}}}
+	capped := pullpanda.RunResult{Summaries: []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 1000}, Capped: true},
+		{Handle: "hubot", Counts: map[string]int{"merged": 3}},
+	}}
+
+	for _, note := range reportNotes(exact) {
+		if strings.Contains(note, "estimate") {
+			t.Errorf("exact counts labeled as estimates: %q", note)
+		}
+	}
+
+	estimate = true
+	notes := reportNotes(exact)
+	estimate = false
+	if !slices.Contains(notes, estimateNote) {
+		t.Errorf("notes with --estimate = %q, want the estimate note", notes)
+	}
+
+	var md bytes.Buffer
+	renderReport(&md, "markdown", capped, []string{"merged"})
+	if !strings.Contains(md.String(), "Counts are estimates for: octocat.") {
+		t.Errorf("markdown doesn't label the capped counts:\n%s", md.String())
+	}
+}
+
+func TestMergeRate(t *testing.T) {
+	tests := []struct {
+		counts map[string]int
+		want   string
+	}{
+		{map[string]int{"merged": 3, "open": 1}, "75.0%"},
+		{map[string]int{"merged": 1, "open": 1, "closed": 1}, "33.3%"},
+		{map[string]int{"merged": 0, "closed": 4}, "0.0%"},
+		{map[string]int{"merged": 5}, "100.0%"},
+		{map[string]int{"merged": 0, "open": 0}, "-"},
+		{nil, "-"},
+	}
+	for _, tt := range tests {
+		if got := mergeRate(tt.counts); got != tt.want {
+			t.Errorf("mergeRate(%v) = %q, want %q", tt.counts, got, tt.want)
+		}
+	}
+
+	if !hasMergeRate([]string{"open", "merged"}) || hasMergeRate([]string{"merged"}) || hasMergeRate([]string{"open", "closed"}) {
+		t.Error("the merge-rate column needs merged PRs and open or closed ones to compare against")
+	}
+}
+
+func TestAboveMinPRs(t *testing.T) {
+	summaries := []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 3, "open": 2}},
+		{Handle: "hubot", Counts: map[string]int{"merged": 1}},
+		{Handle: "monalisa", Counts: map[string]int{"open": 4}},
+	}
+	defer func() { minPRs = 0 }()
+	tests := []struct {
+		minPRs int
+		want   []string
+	}{
+		{0, []string{"octocat", "hubot", "monalisa"}},
+		{4, []string{"octocat", "monalisa"}},
+		{5, []string{"octocat"}},
+		{6, nil},
+	}
+	for _, tt := range tests {
+		minPRs = tt.minPRs
+		var got []string
+		for _, s := range aboveMinPRs(summaries) {
+			got = append(got, s.Handle)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--min-prs %d kept %q, want %q", tt.minPRs, got, tt.want)
+		}
+	}
+}
+
+func TestTopShowsRowsWithTotals(t *testing.T) {
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 3}},
+		{Handle: "hubot", Counts: map[string]int{"merged": 5}},
+		{Handle: "monalisa", Counts: map[string]int{"merged": 3}},
+		{Handle: "defunkt", Counts: map[string]int{"merged": 1}},
+	}}
+	defer func() { top, topInTotals = 0, false }()
+	tests := []struct {
+		top         int
+		topInTotals bool
+		want        []string
+		total       int
+	}{
+		{0, true, []string{"octocat", "hubot", "monalisa", "defunkt"}, 12},
+		// The tie for second goes to the earlier octocat, in config order
+		{2, true, []string{"octocat", "hubot"}, 12},
+		{2, false, []string{"octocat", "hubot"}, 8},
+		{10, false, []string{"octocat", "hubot", "monalisa", "defunkt"}, 12},
+	}
+	for _, tt := range tests {
+		top, topInTotals = tt.top, tt.topInTotals
+		var got []string
+		for _, s := range topSummaries(result.Summaries) {
+			got = append(got, s.Handle)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--top %d kept %q, want %q", tt.top, got, tt.want)
+		}
+
+		var out bytes.Buffer
+		renderReport(&out, "markdown", result, []string{"merged"})
+		rows := 0
+		for _, line := range strings.Split(out.String(), "\n") {
+			for _, s := range result.Summaries {
+				if strings.HasPrefix(line, "| "+s.Handle+" |") {
+					rows++
+				}
+			}
+		}
+		if rows != len(tt.want) {
+			t.Errorf("--top %d rendered %d rows, want %d:\n%s", tt.top, rows, len(tt.want), out.String())
+		}
+		if footer := fmt.Sprintf("| **Total** | %d |", tt.total); !strings.Contains(out.String(), footer) {
+			t.Errorf("--top %d --top-in-totals=%t lacks %q:\n%s", tt.top, tt.topInTotals, footer, out.String())
+		}
+	}
+}
+
+func TestNoFooterDropsTotalsRow(t *testing.T) {
+	avatarServer(t)
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 3}},
+		{Handle: "hubot", Counts: map[string]int{"merged": 1}},
+	}}
+	defer func() { noFooter = false }()
+	tests := []struct {
+		format, footer string
+	}{
+		{"table", "100.0%"},
+		{"markdown", "| **Total** | 4 |"},
+		{"html", "<tfoot>"},
+	}
+	for _, tt := range tests {
+		for _, off := range []bool{false, true} {
+			noFooter = off
+			var out bytes.Buffer
+			renderReport(&out, tt.format, result, []string{"merged"})
+			if got := strings.Contains(out.String(), tt.footer); got == off {
+				t.Errorf("%s with --no-footer=%v: totals row shown = %v:\n%s", tt.format, off, got, out.String())
+			}
+			if !strings.Contains(out.String(), "octocat") {
+				t.Errorf("%s with --no-footer=%v lost the handle rows:\n%s", tt.format, off, out.String())
+			}
+		}
+	}
+}
+
+func TestSortedPRsByKey(t *testing.T) {
+	day := func(d int) *time.Time {
+		date := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	prs := []pullpanda.PullRequest{
+		{Number: 1, Title: "b", Repository: "octo/web", Merged: true, MergedAt: day(2)},
+		{Number: 2, Title: "A", Repository: "octo/api", Merged: true, MergedAt: day(5)},
+		{Number: 3, Title: "b", Repository: "octo/api", Merged: true, MergedAt: day(2)},
+		{Number: 4, Title: "c", Repository: "hub/cli", Merged: true, MergedAt: day(9)},
+	}
+	defer func() { sortPRs = "" }()
+	tests := []struct {
+		key  string
+		want []int
+	}{
+		{"date", []int{4, 2, 1, 3}},
+		{"title", []int{2, 1, 3, 4}},
+		{"repo", []int{4, 2, 3, 1}},
+	}
+	for _, tt := range tests {
+		sortPRs = tt.key
+		var got []int
+		for _, pr := range sortedPRs(prs) {
+			got = append(got, pr.Number)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--sort-prs %s = %v, want %v with ties in fetch order", tt.key, got, tt.want)
+		}
+	}
+	if prs[0].Number != 1 {
+		t.Error("sortedPRs reordered its argument")
+	}
+}
+
+func TestAverageTurnaround(t *testing.T) {
+	created := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	after := func(d time.Duration) *time.Time {
+		merged := created.Add(d)
+		return &merged
+	}
+	summaries := []pullpanda.Summary{
+		{Handle: "octocat", PRs: []pullpanda.PullRequest{
+			{Merged: true, CreatedAt: created, MergedAt: after(2 * time.Hour)},
+			{Merged: true, CreatedAt: created, MergedAt: after(3*24*time.Hour + 4*time.Hour)},
+			// Open and closed PRs, and a merged one without a creation date,
+			// don't count
+			{State: "open", CreatedAt: created},
+			{State: "closed", CreatedAt: created, ClosedAt: after(time.Hour)},
+			{Merged: true, MergedAt: after(time.Hour)},
+		}},
+		{Handle: "hubot", PRs: []pullpanda.PullRequest{
+			{Merged: true, CreatedAt: created, MergedAt: after(30 * time.Minute)},
+		}},
+	}
+	// (2h + 76h + 30m) / 3
+	want := 26*time.Hour + 10*time.Minute
+	if got := averageTurnaround(summaries); got != want {
+		t.Errorf("averageTurnaround = %s, want %s", got, want)
+	}
+	if got := formatTurnaround(want); got != "1d2h" {
+		t.Errorf("formatTurnaround(%s) = %q, want 1d2h", want, got)
+	}
+	if got := averageTurnaround(summaries[:0]); got != 0 || formatTurnaround(got) != "-" {
+		t.Errorf("averageTurnaround of nothing = %s, want 0 shown as -", got)
+	}
+}
+
+func TestPRDate(t *testing.T) {
+	created := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	merged := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	mergedPR := pullpanda.PullRequest{State: "closed", Merged: true, CreatedAt: created, MergedAt: &merged, ClosedAt: &merged}
+	openPR := pullpanda.PullRequest{State: "open", CreatedAt: created}
+	defer func() { prDateField = "" }()
+	tests := []struct {
+		field     string
+		pr        pullpanda.PullRequest
+		want      time.Time
+		wantField string
+	}{
+		{"", mergedPR, merged, "merged"},
+		{"", openPR, created, "created"},
+		{"created", mergedPR, created, "created"},
+		{"closed", mergedPR, merged, "closed"},
+		{"merged", openPR, time.Time{}, "merged"},
+	}
+	for _, tt := range tests {
+		prDateField = tt.field
+		got, field := prDate(tt.pr)
+		if !got.Equal(tt.want) || field != tt.wantField {
+			t.Errorf("--date-field %q of %s PR = %s %s, want %s %s", tt.field, tt.pr.State, field, got, tt.wantField, tt.want)
+		}
+	}
+}
+
+func TestSortedPRsByDateNewestFirst(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	mergedOn := day(6)
+	prs := []pullpanda.PullRequest{
+		{Number: 1, State: "open", CreatedAt: day(4)},
+		{Number: 2, State: "closed", Merged: true, CreatedAt: day(1), MergedAt: &mergedOn},
+		{Number: 3, State: "open", CreatedAt: day(9)},
+		{Number: 4, State: "closed", CreatedAt: day(2)},
+	}
+	defer func() { oldestFirst = false }()
+	for _, tt := range []struct {
+		oldestFirst bool
+		want        []int
+	}{
+		{false, []int{3, 2, 1, 4}},
+		{true, []int{4, 1, 2, 3}},
+	} {
+		oldestFirst = tt.oldestFirst
+		var got []int
+		for _, pr := range sortedPRs(prs) {
+			got = append(got, pr.Number)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--oldest-first=%t order = %v, want %v", tt.oldestFirst, got, tt.want)
+		}
+	}
+}
+
+// TestDuplicateStatusRendersOneColumn loads a config listing merged twice and
+// checks the report has a single merged column with the PRs counted once.
+func TestDuplicateStatusRendersOneColumn(t *testing.T) {
+	var searches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&searches, 1)
+		fmt.Fprint(w, `{"total_count":2,"items":[{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"repository_url":"https://api.github.com/repos/o/r"},{"url":"https://api.github.com/repos/o/r/issues/2","number":2,"repository_url":"https://api.github.com/repos/o/r"}]}`)
+	}))
+	defer srv.Close()
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	defer func() { apiClient = nil }()
+
+	config, err := pullpanda.ConfigLoader{Stdin: strings.NewReader("handles: [octocat]\nstatuses: [merged, open, merged]\n")}.Load(pullpanda.StdinConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := fetchAllPRs(config, pullpanda.DateRange{})
+	if searches != 2 {
+		t.Errorf("sent %d searches, want one per distinct status", searches)
+	}
+	var out bytes.Buffer
+	renderReport(&out, "markdown", result, config.Statuses)
+	if !strings.Contains(out.String(), "| Handle | merged | open | Total |") || !strings.Contains(out.String(), "| octocat | 2 | 2 | 4 |") {
+		t.Errorf("report doesn't have one column per status:\n%s", out.String())
+	}
+}
+
+func TestShareColumnSumsToWholeTeam(t *testing.T) {
+	shares := func(summaries []pullpanda.Summary) ([]string, string) {
+		header, rows, footer := summaryTable(pullpanda.RunResult{Summaries: summaries}, []string{"merged", "open"})
+		column := slices.Index(header, "Share")
+		if column < 0 {
+			t.Fatalf("no Share column in %q", header)
+		}
+		var cells []string
+		for _, row := range rows {
+			cells = append(cells, row[column])
+		}
+		return cells, footer[column]
+	}
+
+	cells, footer := shares([]pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 1}},
+		{Handle: "hubot", Counts: map[string]int{"open": 1}},
+		{Handle: "monalisa", Counts: map[string]int{"merged": 1}},
+	})
+	sum := 0.0
+	for _, cell := range cells {
+		share, err := strconv.ParseFloat(strings.TrimSuffix(cell, "%"), 64)
+		if err != nil {
+			t.Fatalf("share cell %q: %v", cell, err)
+		}
+		sum += share
+	}
+	if sum < 99.8 || sum > 100.2 || footer != "100.0%" {
+		t.Errorf("shares %q sum to %.1f with footer %q, want about 100%%", cells, sum, footer)
+	}
+
+	cells, footer = shares([]pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{}},
+		{Handle: "hubot", Counts: map[string]int{"merged": 0}},
+	})
+	if want := []string{"-", "-"}; !reflect.DeepEqual(cells, want) || footer != "-" {
+		t.Errorf("shares of a team without PRs = %q, footer %q, want dashes", cells, footer)
+	}
+}
+
+// TestHumanizeOnlyFormatsTables checks --humanize groups the thousands in the
+// table, footer included, while the machine-readable outputs keep raw
+// integers.
+func TestHumanizeOnlyFormatsTables(t *testing.T) {
+	humanize = true
+	defer func() { humanize = false }()
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{
+		{Handle: "octocat", Counts: map[string]int{"merged": 1234}},
+	}}
+
+	var table bytes.Buffer
+	renderReport(&table, "table", result, []string{"merged"})
+	if got := strings.Count(table.String(), "1,234"); got < 3 {
+		t.Errorf("table shows 1,234 %d times, want in the count, row total and footer:\n%s", got, table.String())
+	}
+
+	var metrics bytes.Buffer
+	renderReport(&metrics, "prometheus", result, []string{"merged"})
+	if !strings.Contains(metrics.String(), `pullpanda_prs_total{handle="octocat",status="merged"} 1234`) || strings.Contains(metrics.String(), "1,234") {
+		t.Errorf("prometheus output isn't raw:\n%s", metrics.String())
+	}
+
+	var line bytes.Buffer
+	printSummaryLine(&line, result)
+	if !strings.Contains(line.String(), " prs=1234 ") {
+		t.Errorf("summary line %q isn't raw", line.String())
+	}
+}
+
+func TestShowLabelsInDetailedLine(t *testing.T) {
+	var pr pullpanda.PullRequest
+	item := `{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"title":"Fix login","state":"open","created_at":"2024-01-10T00:00:00Z","labels":[{"id":1,"name":"bug","color":"f29513"},{"id":2,"name":"good first issue"}]}`
+	if err := json.Unmarshal([]byte(item), &pr); err != nil {
+		t.Fatal(err)
+	}
+	summaries := []pullpanda.Summary{{Handle: "octocat", PRs: []pullpanda.PullRequest{pr}}}
+	defer func() { showLabels = false }()
+	tests := []struct {
+		show bool
+		want string
+	}{
+		{true, "- [Fix login] (bug, good first issue) https://api.github.com/repos/o/r/issues/1 (created 2024-01-10)\n"},
+		{false, "- [Fix login] https://api.github.com/repos/o/r/issues/1 (created 2024-01-10)\n"},
+	}
+	for _, tt := range tests {
+		showLabels = tt.show
+		var out bytes.Buffer
+		printDetailedPRs(&out, summaries)
+		if got := strings.TrimPrefix(out.String(), "\nDetailed PRs:\n"); got != tt.want {
+			t.Errorf("--show-labels=%v printed %q, want %q", tt.show, got, tt.want)
+		}
+	}
+}
+
+func TestDateFieldInDetailedLine(t *testing.T) {
+	items := []string{
+		`{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"title":"Fix login","state":"closed","created_at":"2024-01-02T09:00:00Z","closed_at":"2024-01-05T10:00:00Z","pull_request":{"merged_at":"2024-01-05T10:00:00Z"}}`,
+		`{"url":"https://api.github.com/repos/o/r/issues/2","number":2,"title":"Add cache","state":"open","created_at":"2024-01-03T09:00:00Z"}`,
+	}
+	prs := make([]pullpanda.PullRequest, len(items))
+	for i, item := range items {
+		if err := json.Unmarshal([]byte(item), &prs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	summaries := []pullpanda.Summary{{Handle: "octocat", PRs: prs}}
+	defer func() { prDateField = "" }()
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"", "- [Fix login] https://api.github.com/repos/o/r/issues/1 (merged 2024-01-05)\n- [Add cache] https://api.github.com/repos/o/r/issues/2 (created 2024-01-03)\n"},
+		{"created", "- [Add cache] https://api.github.com/repos/o/r/issues/2 (created 2024-01-03)\n- [Fix login] https://api.github.com/repos/o/r/issues/1 (created 2024-01-02)\n"},
+		// The open PR has no merge date, so it shows none and sorts last
+		{"merged", "- [Fix login] https://api.github.com/repos/o/r/issues/1 (merged 2024-01-05)\n- [Add cache] https://api.github.com/repos/o/r/issues/2\n"},
+		{"closed", "- [Fix login] https://api.github.com/repos/o/r/issues/1 (closed 2024-01-05)\n- [Add cache] https://api.github.com/repos/o/r/issues/2\n"},
+	}
+	for _, tt := range tests {
+		prDateField = tt.field
+		var out bytes.Buffer
+		printDetailedPRs(&out, summaries)
+		if got := strings.TrimPrefix(out.String(), "\nDetailed PRs:\n"); got != tt.want {
+			t.Errorf("--date-field %q printed %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
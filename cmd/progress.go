@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// startTime is when setupRun started, for the elapsed time of --summary-line.
+var startTime time.Time
+
+// progressEnabled reports whether to show the --progress counter: only when
+// stderr is a terminal, since it redraws one line, and never with --quiet.
+func progressEnabled() bool {
+	return showProgress && !quiet && isTerminal(os.Stderr)
+}
+
+// printProgress returns a pullpanda.Client.OnProgress callback redrawing an
+// "N/M handles fetched" line on w, ended with a newline once all are done.
+func printProgress(w io.Writer) func(done, total int) {
+	return func(done, total int) {
+		fmt.Fprintf(w, "\r%d/%d handles fetched", done, total)
+		if done == total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// warnings collects the warnings of the current run, printed or not, so that
+// --strict can fail it when there are any. The first setupWarnings of them
+// were raised by setupRun about the flags, which hold for every run.
+var (
+	warnings      []string
+	setupWarnings int
+)
+
+// beginRun drops the warnings of the previous report of a batch or --watch,
+// keeping the ones setupRun raised.
+func beginRun() {
+	warnings = warnings[:setupWarnings]
+}
+
+// warnf records a warning and logs it unless --quiet is set.
+func warnf(format string, args ...interface{}) {
+	warnings = append(warnings, fmt.Sprintf(format, args...))
+	if quiet {
+		return
+	}
+	log.Printf("Warning: "+format+"\n", args...)
+}
+
+// printSummaryLine writes the --summary-line status of a run to w as a single
+// line of key=value pairs, e.g. "handles=3 prs=42 failures=0 elapsed=1.5s".
+// It is printed even with --quiet.
+func printSummaryLine(w io.Writer, result pullpanda.RunResult) {
+	fmt.Fprintf(w, "handles=%d prs=%d failures=%d elapsed=%s\n",
+		len(result.Summaries)+len(result.Failures)+len(result.Unfinished), grandTotal(result.Summaries), len(result.Failures),
+		time.Since(startTime).Round(time.Millisecond))
+}
+
+// printExplanation writes the --explain breakdown of result to w: for each
+// handle, the count every search contributed and the query it ran.
+func printExplanation(w io.Writer, result pullpanda.RunResult) {
+	for _, summary := range result.Summaries {
+		for _, partial := range summary.Partials {
+			line := summary.Handle + " " + partial.What
+			if partial.Scope != "" {
+				line += " in " + partial.Scope
+			}
+			fmt.Fprintf(w, "%s: %s\n    %s\n", line, formatCount(partial.Count), partial.Query)
+		}
+	}
+}
+
+// warnTokenExpiry returns a pullpanda.Client.OnTokenExpiry callback warning
+// when the token expires within days.
+func warnTokenExpiry(days int) func(time.Time) {
+	return func(expires time.Time) {
+		left := expires.Sub(now())
+		switch {
+		case left <= 0:
+			warnf("the GitHub token expired on %s", expires.Format("2006-01-02 15:04 MST"))
+		case left <= time.Duration(days)*24*time.Hour:
+			warnf("the GitHub token expires in %s, on %s; renew it before runs start failing", roundDays(left), expires.Format("2006-01-02 15:04 MST"))
+		}
+	}
+}
+
+// roundDays formats d as whole days, or hours when it's under a day.
+func roundDays(d time.Duration) string {
+	n, unit := int(d.Hours()/24), "day"
+	if n == 0 {
+		n, unit = int(d.Hours()), "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
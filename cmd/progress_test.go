@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestProgressReachesAllHandles(t *testing.T) {
+	rateLimitServer(t, 4999)
+	var stderr bytes.Buffer
+	client := apiClient.WithWindow(pullpanda.DateRange{})
+	client.OnProgress = printProgress(&stderr)
+	config := pullpanda.Config{
+		Handles:  []pullpanda.Handle{{Handle: "octocat"}, {Handle: "hubot"}, {Handle: "monalisa"}},
+		Statuses: []string{"merged"},
+	}
+	fetchWith(client, config)
+
+	got := stderr.String()
+	for _, want := range []string{"\r1/3 handles fetched", "\r2/3 handles fetched"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("progress %q lacks %q", got, want)
+		}
+	}
+	if !strings.HasSuffix(got, "\r3/3 handles fetched\n") {
+		t.Errorf("progress %q doesn't end at 3/3 with a newline", got)
+	}
+}
+
+func TestPrintSummaryLineParses(t *testing.T) {
+	startTime = time.Now().Add(-1500 * time.Millisecond)
+	defer func() { startTime = time.Time{} }()
+	result := pullpanda.RunResult{
+		Summaries: []pullpanda.Summary{
+			{Handle: "octocat", Counts: map[string]int{"merged": 30, "open": 2}},
+			{Handle: "hubot", Counts: map[string]int{"merged": 10}},
+		},
+		Failures:   map[string]error{"monalisa": errors.New("boom")},
+		Unfinished: []string{"defunkt"},
+	}
+	var out bytes.Buffer
+	printSummaryLine(&out, result)
+
+	line := out.String()
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("summary line %q isn't a single line", line)
+	}
+	fields := make(map[string]string)
+	for _, pair := range strings.Fields(line) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			t.Fatalf("%q in %q isn't key=value", pair, line)
+		}
+		fields[key] = value
+	}
+	for key, want := range map[string]string{"handles": "4", "prs": "42", "failures": "1"} {
+		if fields[key] != want {
+			t.Errorf("%s = %q, want %q", key, fields[key], want)
+		}
+	}
+	if elapsed, err := time.ParseDuration(fields["elapsed"]); err != nil || elapsed < 1500*time.Millisecond {
+		t.Errorf("elapsed = %q, want a duration of at least 1.5s", fields["elapsed"])
+	}
+}
+
+func TestExplanationMatchesCounts(t *testing.T) {
+	// octo has two merged PRs and acme one; the per-scope lines must add up
+	// to the rendered total
+	reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var items []string
+		q := r.URL.Query().Get("q")
+		for org, numbers := range map[string][]int{"octo": {1, 2}, "acme": {3}} {
+			if !strings.Contains(q, "org:"+org) {
+				continue
+			}
+			for _, n := range numbers {
+				items = append(items, fmt.Sprintf(`{"url":"https://api.github.com/repos/%[1]s/r/issues/%[2]d","number":%[2]d,"repository_url":"https://api.github.com/repos/%[1]s/r"}`, org, n))
+			}
+		}
+		fmt.Fprintf(w, `{"total_count":%d,"items":[%s]}`, len(items), strings.Join(items, ","))
+	})
+	config := pullpanda.Config{
+		Handles:  []pullpanda.Handle{{Handle: "octocat"}},
+		Orgs:     []string{"octo", "acme"},
+		Statuses: []string{"merged"},
+	}
+	result := fetchWith(apiClient.WithWindow(pullpanda.DateRange{}), config)
+	var out bytes.Buffer
+	printExplanation(&out, result)
+
+	got := out.String()
+	for _, want := range []string{"octocat merged in org octo: 2\n", "octocat merged in org acme: 1\n", " org:octo\n", " org:acme\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("explanation %q lacks %q", got, want)
+		}
+	}
+	if total := result.Summaries[0].Counts["merged"]; total != 3 {
+		t.Errorf("merged = %d, want the 3 the scopes add up to", total)
+	}
+}
+
+func TestTokenExpiryWarnsWhenNear(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	tests := []struct {
+		expiresIn time.Duration
+		want      string
+	}{
+		{3*24*time.Hour + time.Hour, "the GitHub token expires in 3 days"},
+		{5*time.Hour + 30*time.Minute, "the GitHub token expires in 5 hours"},
+		{-time.Hour, "the GitHub token expired on"},
+		{20 * 24 * time.Hour, ""},
+	}
+	for _, tt := range tests {
+		expires := time.Now().Add(tt.expiresIn).UTC().Format("2006-01-02 15:04:05 MST")
+		reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("GitHub-Authentication-Token-Expiration", expires)
+			emptySearch(w, r)
+		})
+		client := apiClient.WithWindow(pullpanda.DateRange{})
+		client.OnTokenExpiry = warnTokenExpiry(7)
+		warnings = nil
+		fetchWith(client, pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged", "open"}})
+
+		if tt.want == "" {
+			if len(warnings) != 0 {
+				t.Errorf("expiring in %s warned %q", tt.expiresIn, warnings)
+			}
+			continue
+		}
+		if len(warnings) != 1 || !strings.HasPrefix(warnings[0], tt.want) {
+			t.Errorf("expiring in %s warned %q, want once %q", tt.expiresIn, warnings, tt.want)
+		}
+	}
+}
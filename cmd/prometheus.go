@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// prometheusLabelEscaper escapes label values for the Prometheus text
+// exposition format.
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus writes the summary as metrics in the Prometheus text
+// exposition format, for node_exporter's textfile collector or a
+// Pushgateway. Counts are gauges, as they follow the date range rather than
+// only ever growing.
+func writePrometheus(w io.Writer, result pullpanda.RunResult, statuses []string) {
+	summaries := shownSummaries(result.Summaries)
+
+	fmt.Fprintln(w, "# HELP pullpanda_prs_total PRs per handle and status in the date range.")
+	fmt.Fprintln(w, "# TYPE pullpanda_prs_total gauge")
+	for _, summary := range summaries {
+		for _, status := range statuses {
+			fmt.Fprintf(w, "pullpanda_prs_total{handle=\"%s\",status=\"%s\"} %d\n",
+				prometheusLabelEscaper.Replace(summary.Handle), prometheusLabelEscaper.Replace(status), summary.Counts[status])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP pullpanda_prs_all_total PRs of every handle and status in the date range.")
+	fmt.Fprintln(w, "# TYPE pullpanda_prs_all_total gauge")
+	fmt.Fprintf(w, "pullpanda_prs_all_total %d\n", grandTotal(totaledSummaries(result.Summaries)))
+
+	if includeIssues {
+		fmt.Fprintln(w, "# HELP pullpanda_issues_total Issues opened per handle in the date range.")
+		fmt.Fprintln(w, "# TYPE pullpanda_issues_total gauge")
+		for _, summary := range summaries {
+			fmt.Fprintf(w, "pullpanda_issues_total{handle=\"%s\"} %d\n", prometheusLabelEscaper.Replace(summary.Handle), summary.Issues)
+		}
+	}
+	if includeReviewComments {
+		fmt.Fprintln(w, "# HELP pullpanda_review_comments_total Other authors' PRs commented on per handle in the date range.")
+		fmt.Fprintln(w, "# TYPE pullpanda_review_comments_total gauge")
+		for _, summary := range summaries {
+			fmt.Fprintf(w, "pullpanda_review_comments_total{handle=\"%s\"} %d\n", prometheusLabelEscaper.Replace(summary.Handle), summary.ReviewComments)
+		}
+	}
+
+	if includeCommits {
+		fmt.Fprintln(w, "# HELP pullpanda_commits_total Commits authored per handle in the date range.")
+		fmt.Fprintln(w, "# TYPE pullpanda_commits_total gauge")
+		for _, summary := range summaries {
+			fmt.Fprintf(w, "pullpanda_commits_total{handle=\"%s\"} %d\n", prometheusLabelEscaper.Replace(summary.Handle), summary.Commits)
+		}
+	}
+
+	if includeCoAuthored {
+		fmt.Fprintln(w, "# HELP pullpanda_co_authored_prs_total Merged PRs of others crediting the handle as a co-author in the date range.")
+		fmt.Fprintln(w, "# TYPE pullpanda_co_authored_prs_total gauge")
+		for _, summary := range summaries {
+			fmt.Fprintf(w, "pullpanda_co_authored_prs_total{handle=\"%s\"} %d\n", prometheusLabelEscaper.Replace(summary.Handle), summary.CoAuthored)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP pullpanda_failed_handles Handles whose PRs couldn't be fetched.")
+	fmt.Fprintln(w, "# TYPE pullpanda_failed_handles gauge")
+	fmt.Fprintf(w, "pullpanda_failed_handles %d\n", len(result.Failures))
+
+	fmt.Fprintln(w, "# HELP pullpanda_scrape_timestamp_seconds When the report was generated, in seconds since the epoch.")
+	fmt.Fprintln(w, "# TYPE pullpanda_scrape_timestamp_seconds gauge")
+	fmt.Fprintf(w, "pullpanda_scrape_timestamp_seconds %d\n", now().Unix())
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var (
+	metricName  = `[a-zA-Z_:][a-zA-Z0-9_:]*`
+	commentLine = regexp.MustCompile(`^# (HELP|TYPE) (` + metricName + `) (.+)$`)
+	sampleLine  = regexp.MustCompile(`^(` + metricName + `)(?:\{(.*)\})? (\S+)$`)
+	labelPair   = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\]|\\.)*)",?`)
+)
+
+// parseExposition parses the Prometheus text exposition format strictly
+// enough to catch what a scraper would reject: every sample is of a metric
+// whose TYPE came first, and its labels are well quoted. It returns each
+// sample's value keyed by its name and unescaped labels.
+func parseExposition(r io.Reader) (map[string]float64, error) {
+	samples := make(map[string]float64)
+	typed := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			m := commentLine.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("malformed comment %q", line)
+			}
+			if m[1] == "TYPE" {
+				if m[3] != "gauge" && m[3] != "counter" {
+					return nil, fmt.Errorf("unknown type in %q", line)
+				}
+				typed[m[2]] = true
+			}
+			continue
+		}
+		m := sampleLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("malformed sample %q", line)
+		}
+		if !typed[m[1]] {
+			return nil, fmt.Errorf("sample %q comes before its TYPE", line)
+		}
+		var labels []string
+		for rest := m[2]; rest != ""; {
+			pair := labelPair.FindStringSubmatch(rest)
+			if pair == nil {
+				return nil, fmt.Errorf("malformed labels in %q", line)
+			}
+			value, err := strconv.Unquote(`"` + pair[2] + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("label in %q: %v", line, err)
+			}
+			labels = append(labels, pair[1]+"="+value)
+			rest = rest[len(pair[0]):]
+		}
+		value, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("value in %q: %v", line, err)
+		}
+		key := m[1]
+		if len(labels) > 0 {
+			key += "{" + strings.Join(labels, ",") + "}"
+		}
+		if _, dup := samples[key]; dup {
+			return nil, fmt.Errorf("duplicate sample %s", key)
+		}
+		samples[key] = value
+	}
+	return samples, scanner.Err()
+}
+
+func TestWritePrometheusParses(t *testing.T) {
+	includeIssues, includeCommits = true, true
+	defer func() { includeIssues, includeCommits = false, false }()
+	result := pullpanda.RunResult{
+		Summaries: []pullpanda.Summary{
+			{Handle: "octocat", Counts: map[string]int{"merged": 3, "open": 1}, Issues: 2, Commits: 7},
+			{Handle: `odd"one\`, Counts: map[string]int{"merged": 1}},
+		},
+		Failures: map[string]error{"hubot": errors.New("boom")},
+	}
+	var out bytes.Buffer
+	writePrometheus(&out, result, []string{"merged", "open"})
+
+	samples, err := parseExposition(&out)
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, out.String())
+	}
+	for key, want := range map[string]float64{
+		`pullpanda_prs_total{handle=octocat,status=merged}`:  3,
+		`pullpanda_prs_total{handle=octocat,status=open}`:    1,
+		`pullpanda_prs_total{handle=odd"one\,status=merged}`: 1,
+		`pullpanda_prs_total{handle=odd"one\,status=open}`:   0,
+		`pullpanda_prs_all_total`:                            5,
+		`pullpanda_issues_total{handle=octocat}`:             2,
+		`pullpanda_commits_total{handle=octocat}`:            7,
+		`pullpanda_failed_handles`:                           1,
+	} {
+		if got, ok := samples[key]; !ok || got != want {
+			t.Errorf("%s = %v (present %t), want %v", key, got, ok, want)
+		}
+	}
+	if _, ok := samples["pullpanda_scrape_timestamp_seconds"]; !ok {
+		t.Error("no scrape timestamp")
+	}
+	if _, ok := samples["pullpanda_review_comments_total{handle=octocat}"]; ok {
+		t.Error("review comments exported without --include-review-comments")
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// redactedOwner replaces the owner of every repository with --redact-urls.
+const redactedOwner = "redacted"
+
+// redactedRepo returns the placeholder of repo, e.g. "redacted/3f2a9c1e". It
+// is derived from a hash of the name, so a repo gets the same placeholder in
+// every report and every run.
+func redactedRepo(repo string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(repo)))
+	return redactedOwner + "/" + hex.EncodeToString(sum[:4])
+}
+
+// redactPR returns pr with its repository replaced by its placeholder, in
+// the URLs too, when --redact-urls is set.
+func redactPR(pr pullpanda.PullRequest) pullpanda.PullRequest {
+	if !redactURLs || pr.Repository == "" {
+		return pr
+	}
+	placeholder := redactedRepo(pr.Repository)
+	pr.URL = strings.Replace(pr.URL, "/"+pr.Repository+"/", "/"+placeholder+"/", 1)
+	pr.HTMLURL = strings.Replace(pr.HTMLURL, "/"+pr.Repository+"/", "/"+placeholder+"/", 1)
+	pr.Repository = placeholder
+	return pr
+}
+
+// listedPRs returns prs as the detailed lists show them: sorted by
+// sortedPRs, redacted by redactPR and with titles cut to --max-title-width.
+func listedPRs(prs []pullpanda.PullRequest) []pullpanda.PullRequest {
+	listed := sortedPRs(prs)
+	if !redactURLs && maxTitleWidth == 0 {
+		return listed
+	}
+	shown := make([]pullpanda.PullRequest, len(listed))
+	for i, pr := range listed {
+		shown[i] = redactPR(pr)
+		shown[i].Title = truncateTitle(pr.Title, maxTitleWidth)
+	}
+	return shown
+}
+
+// truncateTitle cuts title to width terminal columns, ending it with an
+// ellipsis, or returns it as is when it fits or width is 0. Width is
+// measured per rune, with wide characters such as CJK taking two columns.
+func truncateTitle(title string, width int) string {
+	if width <= 0 {
+		return title
+	}
+	return runewidth.Truncate(title, width, "…")
+}
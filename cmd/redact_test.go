@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestRedactPRIsConsistent(t *testing.T) {
+	pr := func(repo string, number int) pullpanda.PullRequest {
+		return pullpanda.PullRequest{
+			Number:     number,
+			Repository: repo,
+			URL:        fmt.Sprintf("https://api.github.com/repos/%s/issues/%d", repo, number),
+			HTMLURL:    fmt.Sprintf("https://github.com/%s/pull/%d", repo, number),
+		}
+	}
+	api1, api2, web := pr("octo/api", 1), pr("octo/api", 2), pr("octo/web", 3)
+
+	if got := redactPR(api1); !reflect.DeepEqual(got, api1) {
+		t.Errorf("redacted without --redact-urls: %+v", got)
+	}
+
+	redactURLs = true
+	defer func() { redactURLs = false }()
+	placeholder := regexp.MustCompile(`^redacted/[0-9a-f]{8}$`)
+	a, b, c := redactPR(api1), redactPR(api2), redactPR(web)
+	if !placeholder.MatchString(a.Repository) {
+		t.Errorf("placeholder %q isn't redacted/<hash>", a.Repository)
+	}
+	if a.Repository != b.Repository || a.Repository != redactedRepo("Octo/API") {
+		t.Errorf("octo/api redacted to %q, %q and %q, want one placeholder", a.Repository, b.Repository, redactedRepo("Octo/API"))
+	}
+	if a.Repository == c.Repository {
+		t.Errorf("octo/api and octo/web share the placeholder %q", a.Repository)
+	}
+	for _, got := range []pullpanda.PullRequest{a, b, c} {
+		for _, u := range []string{got.URL, got.HTMLURL} {
+			if strings.Contains(u, "octo/") || !strings.Contains(u, "/"+got.Repository+"/") {
+				t.Errorf("URL %q doesn't carry the placeholder %q", u, got.Repository)
+			}
+		}
+	}
+	if want := "https://github.com/" + a.Repository + "/pull/1"; a.HTMLURL != want {
+		t.Errorf("HTMLURL = %q, want %q", a.HTMLURL, want)
+	}
+}
+
+func TestTruncateTitleKeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		title string
+		width int
+		want  string
+	}{
+		{"Fix the login redirect", 0, "Fix the login redirect"},
+		{"Fix the login redirect", 30, "Fix the login redirect"},
+		{"Fix the login redirect", 10, "Fix the l…"},
+		{"Corrige la sécurité des sessions", 14, "Corrige la sé…"},
+		{"修复登录重定向问题", 7, "修复登…"},
+		{"修复登录重定向问题", 8, "修复登…"},
+		{"Ship 🚀🚀🚀 today", 8, "Ship 🚀…"},
+	}
+	for _, tt := range tests {
+		got := truncateTitle(tt.title, tt.width)
+		if got != tt.want {
+			t.Errorf("truncateTitle(%q, %d) = %q, want %q", tt.title, tt.width, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateTitle(%q, %d) = %q split a rune", tt.title, tt.width, got)
+		}
+		if tt.width > 0 && runewidth.StringWidth(got) > tt.width {
+			t.Errorf("truncateTitle(%q, %d) = %q is %d columns wide", tt.title, tt.width, got, runewidth.StringWidth(got))
+		}
+	}
+}
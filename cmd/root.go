@@ -1,308 +1,591 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
-	"strconv"
-	"sync"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
+	"guidewire.com/pullpanda/pullpanda"
 )
 
-type Config struct {
-	Handles  []string `yaml:"handles"`
-	Orgs     []string `yaml:"orgs"`
-	Repos    []string `yaml:"repos"`
-	Statuses []string `yaml:"statuses"`
-}
-
-type PullRequest struct {
-	URL    string `json:"url"`
-	Title  string `json:"title"`
-	Merged bool   `json:"merged"`
-}
-
-type Summary struct {
-	Handle string
-	Counts map[string]int
-	PRs    []PullRequest
-}
-
 var (
-	configFile string
-	token      string
-	startDate  string
-	endDate    string
-	duration   string
-	enableLog  bool
-	showPRs    bool
+	configFiles           []string
+	token                 string
+	startDate             string
+	endDate               string
+	duration              string
+	enableLog             bool
+	showPRs               bool
+	stepSummary           bool
+	outputFormat          string
+	failOnEmpty           bool
+	codeownersTeam        string
+	countOnly             bool
+	estimate              bool
+	tokenFile             string
+	colorMode             string
+	breakdown             string
+	excludeDrafts         bool
+	onlyDrafts            bool
+	queryExtra            string
+	limit                 int
+	debug                 bool
+	showRateLimit         bool
+	proxy                 string
+	handlesFlag           []string
+	orgsFlag              []string
+	reposFlag             []string
+	mergeScope            bool
+	showSparkline         bool
+	sparklineWeeks        int
+	retries               int
+	outputFile            string
+	includeIssues         bool
+	defaultWindow         string
+	minPRs                int
+	minPRsInTotals        bool
+	useGraphQL            bool
+	noFooter              bool
+	noMerge               bool
+	titleMatch            string
+	matchAffectsCounts    bool
+	teamsFlag             []string
+	sortPRs               string
+	excludeBotsFlag       bool
+	botsFlag              []string
+	caCert                string
+	insecure              bool
+	apiURL                string
+	dryRun                bool
+	timezone              string
+	watchInterval         time.Duration
+	showProgress          bool
+	quiet                 bool
+	includeReviewComments bool
+	appID                 int64
+	appPrivateKey         string
+	installationID        int64
+	userAgentFlag         string
+	strictEnv             bool
+	excludeForks          bool
+	onlyForks             bool
+	summaryLine           bool
+	cacheDir              string
+	cacheTTL              time.Duration
+	humanize              bool
+	showLabels            bool
+	explain               bool
+	tokenExpiryWarn       int
+	aggregate             string
+	oldestFirst           bool
+	tokensFlag            []string
+	apiTokens             []string
+	pageSize              int
+	includeCommits        bool
+	sqlitePath            string
+	redactURLs            bool
+	showTurnaround        bool
+	sinceRelease          string
+	maxTitleWidth         int
+	pathFilter            []string
+	normalizeHandles      bool
+	strict                bool
+	sortSummaries         string
+	includeCoAuthored     bool
+	skipInactiveRepos     bool
+	prDateField           string
+	top                   int
+	topInTotals           bool
+	acceptFlag            []string
+	emptyAs               string
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "pullpanda",
 	Short: "CLI to measure open-source contributions by fetching pull requests of specified GitHub handles",
 	Run: func(cmd *cobra.Command, args []string) {
-		config := loadConfig(configFile)
-		if enableLog {
-			log.Printf("Loaded config: %+v\n", config)
+		if err := validateOutputFormat(outputFormat); err != nil {
+			log.Fatal(err)
+		}
+		config := setupRun(cmd)
+		if dryRun {
+			window, err := resolveDateRange()
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, u := range apiClient.WithWindow(window).SearchURLs(config) {
+				fmt.Println(redactToken(u))
+			}
+			return
+		}
+		if watchInterval > 0 {
+			os.Exit(watch(watchInterval, func() int { return runReport(config) }))
 		}
-		summaries := fetchAllPRs(config)
-		printSummaryTable(summaries, config.Statuses)
-		if showPRs {
-			printDetailedPRs(summaries)
+		if code := runReport(config); code != 0 {
+			os.Exit(code)
 		}
 	},
 }
 
-func Execute() {
-	rootCmd.PersistentFlags().StringVar(&configFile, "config", "config.yaml", "config file (default is config.yaml)")
-	rootCmd.PersistentFlags().StringVar(&token, "token", "", "GitHub personal access token")
-	rootCmd.PersistentFlags().StringVar(&startDate, "start-date", "", "Start date in YYYY-MM-DD format")
-	rootCmd.PersistentFlags().StringVar(&endDate, "end-date", "", "End date in YYYY-MM-DD format")
-	rootCmd.PersistentFlags().StringVar(&duration, "duration", "", "Duration like 1mo, 1w, 1d, 1h, 1m, 1s")
-	rootCmd.PersistentFlags().BoolVar(&enableLog, "enable-log", false, "Enable logging")
-	rootCmd.PersistentFlags().BoolVar(&showPRs, "show-prs", false, "Show detailed PRs after the summary table")
-	rootCmd.MarkPersistentFlagRequired("token")
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// runReport fetches and renders one report for the current date range and
+// returns the exit code it calls for.
+func runReport(config pullpanda.Config) int {
+	beginRun()
+	window, err := resolveDateRange()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if breakdown != "" {
+		buckets, err := breakdownBuckets(window, breakdown)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result, matrix := fetchBreakdown(config, buckets)
+		logFailures(result)
+		if len(result.Unfinished) > 0 {
+			return exitCode(result, failOnEmpty)
+		}
+		out, err := openOutput(window)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printBreakdownTable(out, result.Summaries, buckets, matrix, breakdown)
+		if err := closeOutput(out); err != nil {
+			log.Fatal(err)
+		}
+		if showRateLimit {
+			printRateLimit(os.Stderr)
+		}
+		return exitCode(result, failOnEmpty)
+	}
+	out, err := openOutput(window)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "jsonl" {
+		apiClient.OnPR = jsonlStreamer(out)
+	}
+	result := fetchAllPRs(config, window)
+	result.Summaries = sortedSummaries(result.Summaries, config)
+	logFailures(result)
+	if showSparkline {
+		if err := addSparklines(config, window, result); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if aggregate == "org" {
+		renderOrgReport(out, result, config)
+	} else {
+		renderReport(out, outputFormat, result, config.Statuses)
+	}
+	if err := closeOutput(out); err != nil {
+		log.Fatal(err)
+	}
+	if stepSummary {
+		writeStepSummary(result, config.Statuses)
+	}
+	if sqlitePath != "" {
+		if err := writeSQLite(sqlitePath, window, result, config.Statuses); err != nil {
+			log.Fatal(err)
+		}
 	}
+	if explain {
+		printExplanation(os.Stderr, result)
+	}
+	if showRateLimit {
+		printRateLimit(os.Stderr)
+	}
+	if summaryLine {
+		printSummaryLine(os.Stderr, result)
+	}
+	return exitCode(result, failOnEmpty)
 }
 
-func loadConfig(configFile string) Config {
-	file, err := ioutil.ReadFile(configFile)
+// setupRun resolves the token, loads the config and checks the flags shared
+// by every command that fetches PRs, exiting on the first problem.
+func setupRun(cmd *cobra.Command) pullpanda.Config {
+	startTime = time.Now()
+	config, err := loadRunConfig(cmd.Flags().Changed("config"))
 	if err != nil {
-		log.Fatalf("Error reading config file: %v", err)
+		log.Fatal(err)
 	}
-
-	var config Config
-	if err := yaml.Unmarshal(file, &config); err != nil {
-		log.Fatalf("Error parsing config file: %v", err)
+	config = excludeBots(config)
+	// A dry run doesn't send the queries, so it works without a token
+	if usesApp() {
+		if appAuth, err = loadAppAuth(); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := requireToken(config.Tokens); err != nil && !dryRun {
+		log.Fatal(err)
 	}
-
-	// Set default statuses if not provided
-	if len(config.Statuses) == 0 {
-		config.Statuses = []string{"merged"}
+	if err := validateColorMode(colorMode); err != nil {
+		log.Fatal(err)
 	}
-
+	if proxyURL, err = parseProxy(proxy); err != nil {
+		log.Fatal(err)
+	}
+	if err := validatePRSortKey(sortPRs); err != nil {
+		log.Fatal(err)
+	}
+	if err := validatePRDateField(prDateField); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateSummarySortKey(sortSummaries); err != nil {
+		log.Fatal(err)
+	}
+	if minPRs < 0 {
+		log.Fatal("--min-prs can't be negative")
+	}
+	if top < 0 {
+		log.Fatal("--top can't be negative")
+	}
+	if tlsConfig, err = buildTLSConfig(caCert, insecure); err != nil {
+		log.Fatal(err)
+	}
+	if location, err = time.LoadLocation(timezone); err != nil {
+		log.Fatalf("invalid --timezone %q: %v", timezone, err)
+	}
+	if retries < 0 {
+		log.Fatal("--retries can't be negative")
+	}
+	if maxTitleWidth < 0 {
+		log.Fatal("--max-title-width can't be negative")
+	}
+	if err := validatePageSize(pageSize); err != nil {
+		log.Fatal(err)
+	}
+	if sparklineWeeks < 1 {
+		log.Fatal("--sparkline-weeks must be at least 1")
+	}
+	checkSparkline()
+	if excludeDrafts && onlyDrafts {
+		log.Fatal("--exclude-drafts and --only-drafts are mutually exclusive")
+	}
+	if excludeForks && onlyForks {
+		log.Fatal("--exclude-forks and --only-forks are mutually exclusive")
+	}
+	if (excludeForks || onlyForks) && (countOnly || useGraphQL) {
+		log.Fatal("--exclude-forks and --only-forks need every PR and can't be combined with --count-only or --use-graphql")
+	}
+	if err := validateQueryExtra(queryExtra); err != nil {
+		log.Fatal(err)
+	}
+	// An estimate is a count-only run with the report labeled as such
+	if estimate {
+		countOnly = true
+	}
+	if countOnly && (showPRs || codeownersTeam != "") {
+		log.Fatal("--count-only can't be combined with --show-prs or --codeowners-team")
+	}
+	if titleRegexp, err = compileTitleMatch(titleMatch); err != nil {
+		log.Fatal(err)
+	}
+	if matchAffectsCounts && titleMatch == "" {
+		log.Fatal("--match-affects-counts needs --title-match")
+	}
+	if matchAffectsCounts && (countOnly || useGraphQL) {
+		log.Fatal("--match-affects-counts needs every PR and can't be combined with --count-only or --use-graphql")
+	}
+	if err := validateOutputFile(outputFile); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateWatch(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateAggregate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateBreakdown(); err != nil {
+		log.Fatal(err)
+	}
+	if outputFormat == "jsonl" && (countOnly || useGraphQL) {
+		log.Fatal("--output jsonl streams PRs and can't be combined with --count-only or --use-graphql, which only fetch counts")
+	}
+	if showTurnaround && (countOnly || useGraphQL) {
+		log.Fatal("--show-turnaround needs the merged PRs and can't be combined with --count-only or --use-graphql")
+	}
+	if len(pathFilter) > 0 && (countOnly || useGraphQL) {
+		log.Fatal("--path-filter needs every PR and can't be combined with --count-only or --use-graphql")
+	}
+	if len(pathFilter) > 0 && !dryRun {
+		warnf("--path-filter lists the changed files of every PR found, one extra request per PR; narrow the range and scopes for large runs")
+	}
+	if includeCoAuthored && (countOnly || useGraphQL) {
+		log.Fatal("--include-co-authored reads the commits of every merged PR and can't be combined with --count-only or --use-graphql")
+	}
+	if includeCoAuthored && !dryRun {
+		warnf("--include-co-authored lists every merged PR in the searched orgs and repos and the commits of each, one extra request per PR; narrow the range and scopes for large runs")
+	}
+	if useGraphQL && includeCommits {
+		log.Fatal("--include-commits uses the commit search, which the GraphQL API lacks, and can't be combined with --use-graphql")
+	}
+	if useGraphQL && (showPRs || codeownersTeam != "") {
+		log.Fatal("--use-graphql only fetches counts and can't be combined with --show-prs or --codeowners-team")
+	}
+	if codeownersTeam != "" && !strings.HasPrefix(codeownersTeam, "@") {
+		codeownersTeam = "@" + codeownersTeam
+	}
+	apiClient = newClient()
+	if config, err = expandTeams(config); err != nil {
+		log.Fatal(err)
+	}
+	if config, err = requireHandles(config); err != nil {
+		log.Fatal(err)
+	}
+	if err := checkConfig(config); err != nil {
+		log.Fatal(err)
+	}
+	logConfig(config)
+	setupWarnings = len(warnings)
 	return config
 }
 
-func parseDuration(duration string) (time.Duration, error) {
-	if len(duration) < 2 {
-		return 0, fmt.Errorf("invalid duration format")
+// requireToken resolves the GitHub token for commands that talk to the API,
+// preferring --token, then the tokens of --tokens or the config, then
+// --token-file, then the GITHUB_TOKEN env var, then the token stored by
+// login.
+func requireToken(tokens []string) error {
+	if token != "" {
+		return nil
 	}
-
-	unit := duration[len(duration)-1:]
-	value := duration[:len(duration)-1]
-
-	// Handle multi-character units like "mo" (months)
-	if unit == "o" && len(duration) > 2 && duration[len(duration)-2:] == "mo" {
-		unit = "mo"
-		value = duration[:len(duration)-2]
+	if len(tokens) > 0 {
+		apiTokens = tokens
+		token = tokens[0]
+		return nil
 	}
-
-	numValue, err := strconv.Atoi(value)
-	if err != nil {
-		return 0, err
+	if tokenFile != "" {
+		contents, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return fmt.Errorf("error reading token file: %w", err)
+		}
+		token = strings.TrimSpace(string(contents))
+		if token == "" {
+			return fmt.Errorf("token file %s is empty", tokenFile)
+		}
+		return nil
 	}
-
-	switch unit {
-	case "s":
-		return time.Duration(numValue) * time.Second, nil
-	case "m":
-		return time.Duration(numValue) * time.Minute, nil
-	case "h":
-		return time.Duration(numValue) * time.Hour, nil
-	case "d":
-		return time.Duration(numValue) * 24 * time.Hour, nil
-	case "w":
-		return time.Duration(numValue) * 7 * 24 * time.Hour, nil
-	case "mo":
-		return time.Duration(numValue) * 30 * 24 * time.Hour, nil
-	default:
-		return 0, fmt.Errorf("invalid duration unit")
+	if token = os.Getenv("GITHUB_TOKEN"); token != "" {
+		return nil
+	}
+	if token = storedToken(); token != "" {
+		return nil
 	}
+	return fmt.Errorf("no GitHub token provided; use --token, --tokens, --token-file, the GITHUB_TOKEN env var or pullpanda login")
 }
 
-func fetchAllPRs(config Config) []Summary {
-	var wg sync.WaitGroup
-	summaries := make([]Summary, len(config.Handles))
+// Exit codes of a report run. Usage and config errors exit with 1 as well.
+const (
+	exitOK           = 0
+	exitEmpty        = 1
+	exitFetchFailure = 2
+	exitWarnings     = 3
+	// exitInterrupted is the shell's code for a process ended by SIGINT.
+	exitInterrupted = 130
+)
 
-	for i, handle := range config.Handles {
-		wg.Add(1)
-		go func(i int, handle string) {
-			defer wg.Done()
-			summaries[i] = fetchPRs(handle, config.Orgs, config.Repos, config.Statuses)
-		}(i, handle)
+// exitCode decides the process exit status once the report has been rendered.
+// Failed handles take precedence over warnings and an empty result, since the
+// missing handles are the likely reason nothing was found.
+func exitCode(result pullpanda.RunResult, failOnEmpty bool) int {
+	if len(result.Unfinished) > 0 {
+		return exitInterrupted
 	}
-
-	wg.Wait()
-	return summaries
+	if len(result.Failures) > 0 {
+		return exitFetchFailure
+	}
+	if strictFailure() {
+		return exitWarnings
+	}
+	if failOnEmpty && grandTotal(result.Summaries) == 0 {
+		log.Println("No pull requests found, failing because --fail-on-empty is set")
+		return exitEmpty
+	}
+	return exitOK
 }
 
-func fetchPRs(handle string, orgs []string, repos []string, statuses []string) Summary {
-	client := &http.Client{}
-	summary := Summary{
-		Handle: handle,
-		Counts: make(map[string]int),
+// strictFailure reports whether --strict fails the run for the warnings it
+// raised, logging why.
+func strictFailure() bool {
+	if !strict || len(warnings) == 0 {
+		return false
 	}
+	log.Printf("Failing because --strict is set and the run raised %d warning(s)\n", len(warnings))
+	return true
+}
 
-	for _, status := range statuses {
-		query := fmt.Sprintf("author:%s is:pr is:%s", handle, status)
-
-		// Calculate startDate if duration is provided
-		if duration != "" {
-			parsedDuration, err := parseDuration(duration)
-			if err != nil {
-				log.Fatalf("Error parsing duration: %v", err)
-			}
-			startTime := time.Now().Add(-parsedDuration)
-			startDate = startTime.Format("2006-01-02")
-			if enableLog {
-				log.Printf("Parsed duration: %s, start date: %s\n", duration, startDate)
-			}
-		}
-
-		if status == "merged" {
-			if startDate != "" {
-				query += fmt.Sprintf(" merged:>=%s", startDate)
-			}
-			if endDate != "" {
-				query += fmt.Sprintf(" merged:<=%s", endDate)
-			}
-		} else {
-			if startDate != "" {
-				query += fmt.Sprintf(" created:>=%s", startDate)
-			}
-			if endDate != "" {
-				query += fmt.Sprintf(" created:<=%s", endDate)
-			}
-		}
-
-		if len(orgs) > 0 {
-			for _, org := range orgs {
-				orgQuery := query + fmt.Sprintf(" org:%s", org)
-				escapedQuery := url.QueryEscape(orgQuery)
-				url := fmt.Sprintf("https://api.github.com/search/issues?q=%s", escapedQuery)
-
-				if enableLog {
-					log.Printf("Fetching %s PRs for %s in org %s with query: %s\n", status, handle, org, url)
-				}
-
-				prs := makeRequest(client, url)
-				summary.Counts[status] += len(prs)
-				summary.PRs = append(summary.PRs, prs...)
-			}
-		} else if len(repos) > 0 {
-			for _, repo := range repos {
-				repoQuery := query + fmt.Sprintf(" repo:%s", repo)
-				escapedQuery := url.QueryEscape(repoQuery)
-				url := fmt.Sprintf("https://api.github.com/search/issues?q=%s", escapedQuery)
-
-				if enableLog {
-					log.Printf("Fetching %s PRs for %s in repo %s with query: %s\n", status, handle, repo, url)
-				}
-
-				prs := makeRequest(client, url)
-				summary.Counts[status] += len(prs)
-				summary.PRs = append(summary.PRs, prs...)
-			}
-		} else {
-			escapedQuery := url.QueryEscape(query)
-			url := fmt.Sprintf("https://api.github.com/search/issues?q=%s", escapedQuery)
-
-			if enableLog {
-				log.Printf("Fetching %s PRs for %s with query: %s\n", status, handle, url)
-			}
-
-			prs := makeRequest(client, url)
-			summary.Counts[status] += len(prs)
-			summary.PRs = append(summary.PRs, prs...)
+func grandTotal(summaries []pullpanda.Summary) int {
+	total := 0
+	for _, summary := range summaries {
+		for _, count := range summary.Counts {
+			total += count
 		}
 	}
-
-	return summary
+	return total
 }
 
-func makeRequest(client *http.Client, url string) []PullRequest {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Fatalf("Error creating request: %v", err)
+func Execute() {
+	rootCmd.PersistentFlags().StringArrayVar(&configFiles, "config", nil, "config file, - for stdin, repeat to merge several (default is the first of ./config.yaml, $XDG_CONFIG_HOME/pullpanda/config.yaml and ~/.pullpanda.yaml)")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "", "GitHub personal access token")
+	rootCmd.PersistentFlags().StringSliceVar(&tokensFlag, "tokens", nil, "Comma-separated GitHub tokens to use in turn, spreading the requests over their rate limits")
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "Read the GitHub token from this file")
+	rootCmd.PersistentFlags().StringVar(&startDate, "start-date", "", "Start date in YYYY-MM-DD format, or \"now\"")
+	rootCmd.PersistentFlags().StringVar(&endDate, "end-date", "", "End date in YYYY-MM-DD format, or \"now\"")
+	rootCmd.PersistentFlags().StringVar(&sinceRelease, "since-release", "", "Start the range on the day the latest release of this owner/repo was published")
+	rootCmd.PersistentFlags().StringVar(&duration, "duration", "", "Duration like 1mo, 1w, 1d, 1h, 1m, 1s")
+	rootCmd.PersistentFlags().BoolVar(&enableLog, "enable-log", false, "Enable logging")
+	rootCmd.PersistentFlags().BoolVar(&showPRs, "show-prs", false, "Show detailed PRs after the summary table")
+	rootCmd.PersistentFlags().BoolVar(&stepSummary, "step-summary", false, "Append a markdown report to the file named by GITHUB_STEP_SUMMARY")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "table", "Output format: table, markdown, html, jsonl or prometheus")
+	rootCmd.PersistentFlags().BoolVar(&failOnEmpty, "fail-on-empty", false, "Exit with a non-zero status when no PRs are found")
+	rootCmd.PersistentFlags().StringVar(&codeownersTeam, "codeowners-team", "", "Only count PRs touching paths owned by this CODEOWNERS owner, e.g. @org/team-x")
+	rootCmd.PersistentFlags().BoolVar(&countOnly, "count-only", false, "Only fetch the counts, with one request per query and no PR details")
+	rootCmd.PersistentFlags().BoolVar(&estimate, "estimate", false, "Only fetch the counts like --count-only, labeling them as estimates for a quick ballpark")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", "auto", "Colorize the summary table: auto, always or never")
+	rootCmd.PersistentFlags().StringVar(&breakdown, "breakdown", "", "Break the totals down into weekly or monthly buckets")
+	rootCmd.PersistentFlags().BoolVar(&excludeDrafts, "exclude-drafts", false, "Don't count draft PRs")
+	rootCmd.PersistentFlags().BoolVar(&onlyDrafts, "only-drafts", false, "Only count draft PRs")
+	rootCmd.PersistentFlags().BoolVar(&excludeForks, "exclude-forks", false, "Leave out PRs in forked repositories")
+	rootCmd.PersistentFlags().BoolVar(&onlyForks, "only-forks", false, "Only count PRs in forked repositories")
+	rootCmd.PersistentFlags().StringVar(&queryExtra, "query-extra", "", "Extra search qualifiers appended to every query, e.g. \"label:bug language:go\"")
+	rootCmd.PersistentFlags().IntVar(&pageSize, "page-size", 100, "Number of PRs requested per search page, 1 to 100")
+	rootCmd.PersistentFlags().IntVar(&limit, "limit", 0, "Maximum number of PRs to collect per handle, 0 for no limit")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log every HTTP request with its status, timing and rate-limit headers to stderr")
+	rootCmd.PersistentFlags().BoolVar(&showRateLimit, "show-rate-limit", false, "Print the lowest remaining API rate limit seen during the run to stderr")
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "Proxy URL for GitHub API requests, defaults to HTTP_PROXY/HTTPS_PROXY")
+	rootCmd.PersistentFlags().StringSliceVar(&handlesFlag, "handles", nil, "Comma-separated handles, overriding the config")
+	rootCmd.PersistentFlags().StringSliceVar(&orgsFlag, "orgs", nil, "Comma-separated orgs, overriding the config")
+	rootCmd.PersistentFlags().StringSliceVar(&reposFlag, "repos", nil, "Comma-separated repos, overriding the config")
+	rootCmd.PersistentFlags().BoolVar(&mergeScope, "merge-scope", false, "Merge --handles, --orgs and --repos into the config lists instead of replacing them")
+	rootCmd.PersistentFlags().BoolVar(&showSparkline, "sparkline", false, "Add a sparkline column with weekly activity per handle")
+	rootCmd.PersistentFlags().IntVar(&sparklineWeeks, "sparkline-weeks", defaultSparklineWeeks, "Number of weeks at the end of the date range shown by --sparkline")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 1, "Number of times to retry GitHub API requests failing with a 500, 502, 503 or 504")
+	rootCmd.PersistentFlags().StringVar(&outputFile, "output-file", "", "Write the report to this file instead of stdout")
+	rootCmd.PersistentFlags().BoolVar(&includeIssues, "include-issues", false, "Also count issues opened by each handle, in a separate Issues column")
+	rootCmd.PersistentFlags().BoolVar(&includeCommits, "include-commits", false, "Also count the commits each handle authored, in a separate Commits column")
+	rootCmd.PersistentFlags().BoolVar(&includeReviewComments, "include-review-comments", false, "Also count other authors' PRs each handle commented on, in a separate Review comments column")
+	rootCmd.PersistentFlags().StringVar(&defaultWindow, "default-window", "", "Length of the range, e.g. 90d, used when no start date is given")
+	rootCmd.PersistentFlags().IntVar(&minPRs, "min-prs", 0, "Hide handles with fewer PRs in total than this")
+	rootCmd.PersistentFlags().BoolVar(&minPRsInTotals, "min-prs-in-totals", true, "Keep handles hidden by --min-prs in the footer totals")
+	rootCmd.PersistentFlags().BoolVar(&useGraphQL, "use-graphql", false, "Fetch counts through the GraphQL API, batching many searches per request")
+	rootCmd.PersistentFlags().BoolVar(&noFooter, "no-footer", false, "Leave out the totals row of the summary table")
+	rootCmd.PersistentFlags().BoolVar(&noMerge, "no-merge", false, "Don't merge repeated cells of the Handle column in the summary table")
+	rootCmd.PersistentFlags().StringVar(&titleMatch, "title-match", "", "Only list PRs whose title matches this regular expression")
+	rootCmd.PersistentFlags().BoolVar(&matchAffectsCounts, "match-affects-counts", false, "Only count PRs matching --title-match too")
+	rootCmd.PersistentFlags().StringSliceVar(&teamsFlag, "team", nil, "Count the members of these GitHub teams, given as org/slug, as handles")
+	rootCmd.PersistentFlags().StringVar(&sortPRs, "sort-prs", "", "Sort each handle's detailed PRs by date (the default), title or repo")
+	rootCmd.PersistentFlags().BoolVar(&excludeBotsFlag, "exclude-bots", false, "Leave PRs by bot accounts out of every search")
+	rootCmd.PersistentFlags().StringSliceVar(&botsFlag, "bots", defaultBots, "Bot accounts excluded by --exclude-bots")
+	rootCmd.PersistentFlags().StringVar(&caCert, "ca-cert", "", "PEM CA bundle trusted in addition to the system roots, e.g. for GitHub Enterprise")
+	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification, for testing only")
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", pullpanda.DefaultBaseURL, "GitHub API base URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the search queries that would be sent and exit without sending them")
+	rootCmd.PersistentFlags().StringVar(&timezone, "timezone", "UTC", "IANA time zone, e.g. Europe/Berlin, that dates and durations are interpreted in")
+	rootCmd.Flags().DurationVar(&watchInterval, "watch", 0, "Re-run and redraw the report on this interval, e.g. 5m, until interrupted")
+	rootCmd.PersistentFlags().BoolVar(&showProgress, "progress", false, "Show an N/M handles fetched counter on stderr while fetching")
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Don't print warnings or the progress counter")
+	rootCmd.PersistentFlags().Int64Var(&appID, "app-id", 0, "Authenticate as this GitHub App, with --app-private-key and --installation-id, instead of a token")
+	rootCmd.PersistentFlags().StringVar(&appPrivateKey, "app-private-key", "", "Path to the GitHub App's PEM private key")
+	rootCmd.PersistentFlags().Int64Var(&installationID, "installation-id", 0, "ID of the GitHub App installation to get a token for")
+	rootCmd.PersistentFlags().StringVar(&userAgentFlag, "user-agent", "", "User-Agent header sent to GitHub (default \"pullpanda/<version>\")")
+	rootCmd.PersistentFlags().BoolVar(&strictEnv, "strict-env", false, "Fail when the config references an undefined environment variable")
+	rootCmd.PersistentFlags().BoolVar(&summaryLine, "summary-line", false, "Print a handles=N prs=N failures=N elapsed=D line to stderr at the end, even with --quiet")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Cache every search result page on disk in this directory")
+	rootCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", time.Hour, "How long pages cached with --cache-dir are reused, 0 for forever")
+	rootCmd.PersistentFlags().BoolVar(&humanize, "humanize", false, "Format counts in the tables with thousands separators, e.g. 12,345")
+	rootCmd.PersistentFlags().BoolVar(&showLabels, "show-labels", false, "Show each PR's labels after its title in the detailed PR list")
+	rootCmd.PersistentFlags().BoolVar(&explain, "explain", false, "After the report, print to stderr the count each search contributed, per handle")
+	rootCmd.PersistentFlags().IntVar(&tokenExpiryWarn, "token-expiry-warn", 7, "Warn when the token expires within this many days, 0 to disable")
+	rootCmd.PersistentFlags().StringVar(&aggregate, "aggregate", "", "Roll the PRs up per org instead of per handle (supported: org)")
+	rootCmd.PersistentFlags().BoolVar(&oldestFirst, "oldest-first", false, "List the detailed PRs oldest first instead of newest first")
+	rootCmd.PersistentFlags().StringVar(&sqlitePath, "sqlite", "", "After each run, store the counts per handle and status in this SQLite database")
+	rootCmd.PersistentFlags().BoolVar(&redactURLs, "redact-urls", false, "Replace repository names in the detailed PR lists and JSON output with stable placeholders")
+	rootCmd.PersistentFlags().BoolVar(&showTurnaround, "show-turnaround", false, "Add an Avg turnaround column with the mean time from creation to merge of the merged PRs")
+	rootCmd.PersistentFlags().IntVar(&maxTitleWidth, "max-title-width", 0, "Cut PR titles in the detailed lists to this many columns, ending them with an ellipsis, 0 for no limit")
+	rootCmd.PersistentFlags().StringSliceVar(&pathFilter, "path-filter", nil, "Only count PRs changing a file matching one of these gitignore-style patterns, e.g. services/api/ (one extra request per PR)")
+	rootCmd.PersistentFlags().BoolVar(&normalizeHandles, "normalize-handles", false, "Lowercase the handles and merge the ones differing only in case into one row")
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Fail with a non-zero status when the run raised any warning")
+	rootCmd.PersistentFlags().StringVar(&sortSummaries, "sort", "config", "Order the handles by config (the default), handle or total")
+	rootCmd.PersistentFlags().BoolVar(&includeCoAuthored, "include-co-authored", false, "Also count the merged PRs of others crediting each handle in a Co-authored-by trailer, in a separate Co-authored column")
+	rootCmd.PersistentFlags().BoolVar(&skipInactiveRepos, "skip-inactive-repos", false, "Don't search the configured repos that weren't pushed to or updated since the start date")
+	rootCmd.PersistentFlags().StringVar(&prDateField, "date-field", "", "Show and sort the detailed PRs by their created, merged or closed date (default is merged for merged PRs, created otherwise)")
+	rootCmd.PersistentFlags().IntVar(&top, "top", 0, "Only show the N handles with the most PRs in total")
+	rootCmd.PersistentFlags().BoolVar(&topInTotals, "top-in-totals", true, "Keep handles hidden by --top in the footer totals")
+	rootCmd.PersistentFlags().StringSliceVar(&acceptFlag, "accept", nil, "Extra media types, e.g. API previews, to send in the Accept header after the default one (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&emptyAs, "empty-as", "zero", "Show zero counts in the tables as 0 (zero), - (dash) or this placeholder")
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+}
 
-	req.Header.Set("Authorization", "token "+token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+// logFailures reports failed handles on stderr, sorted by handle.
+func logFailures(result pullpanda.RunResult) {
+	for _, handle := range result.FailedHandles() {
+		log.Printf("Error fetching PRs for %s: %v\n", handle, result.Failures[handle])
+	}
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("Error making request: %v", err)
+// errInterrupted marks the error of a run interrupted before every handle
+// was fetched.
+var errInterrupted = errors.New("interrupted")
+
+// failuresError combines the failed and unfinished handles into one error,
+// or nil when every handle was fetched. Commands that line up several runs
+// need all of them.
+func failuresError(result pullpanda.RunResult) error {
+	var errs []error
+	for _, handle := range result.FailedHandles() {
+		errs = append(errs, fmt.Errorf("fetching PRs for %s: %w", handle, result.Failures[handle]))
 	}
-	defer resp.Body.Close()
+	if len(result.Unfinished) > 0 {
+		errs = append(errs, fmt.Errorf("%w before fetching %s", errInterrupted, strings.Join(result.Unfinished, ", ")))
+	}
+	return errors.Join(errs...)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Error: received non-200 response code %d", resp.StatusCode)
+// fatalFetch logs the failuresError err and exits, with exitInterrupted when
+// the run was interrupted.
+func fatalFetch(err error) {
+	log.Print(err)
+	if errors.Is(err, errInterrupted) {
+		os.Exit(exitInterrupted)
 	}
+	os.Exit(1)
+}
 
-	var result struct {
-		Items []PullRequest `json:"items"`
+// validateQueryExtra rejects author: qualifiers, which would conflict with the
+// author:<handle> every query is built around. Exclusions like -author: are
+// fine.
+// compileTitleMatch compiles the --title-match pattern, nil when unset.
+func compileTitleMatch(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Fatalf("Error decoding response: %v", err)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --title-match pattern: %w", err)
 	}
-
-	return result.Items
+	return re, nil
 }
 
-func printSummaryTable(summaries []Summary, statuses []string) {
-	table := tablewriter.NewWriter(os.Stdout)
-	header := append([]string{"Handle"}, statuses...)
-	header = append(header, "Total")
-	table.SetHeader(header)
-
-	var totalCounts = make(map[string]int)
-
-	for _, summary := range summaries {
-		row := []string{summary.Handle}
-		total := 0
-		for _, status := range statuses {
-			count := summary.Counts[status]
-			row = append(row, strconv.Itoa(count))
-			total += count
-			totalCounts[status] += count
-		}
-		row = append(row, strconv.Itoa(total))
-		table.Append(row)
+func validatePageSize(size int) error {
+	if size < 1 || size > 100 {
+		return fmt.Errorf("--page-size must be between 1 and 100, got %d", size)
 	}
-
-	totalRow := []string{"Total"}
-	grandTotal := 0
-	for _, status := range statuses {
-		total := totalCounts[status]
-		totalRow = append(totalRow, strconv.Itoa(total))
-		grandTotal += total
-	}
-	totalRow = append(totalRow, strconv.Itoa(grandTotal))
-	table.SetFooter(totalRow)
-	table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
-	table.SetAutoMergeCellsByColumnIndex([]int{0})
-
-	table.Render()
+	return nil
 }
 
-func printDetailedPRs(summaries []Summary) {
-	fmt.Println("\nDetailed PRs:")
-	for _, summary := range summaries {
-		for _, pr := range summary.PRs {
-			fmt.Printf("- [%s] %s\n", pr.Title, pr.URL)
+func validateQueryExtra(extra string) error {
+	for _, term := range strings.Fields(extra) {
+		if strings.HasPrefix(strings.ToLower(term), "author:") {
+			return fmt.Errorf("--query-extra can't contain %q; the author is set from the configured handles", term)
 		}
 	}
+	return nil
 }
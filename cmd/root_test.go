@@ -0,0 +1,454 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestFailuresErrorCoversUnfinishedHandles(t *testing.T) {
+	result := pullpanda.RunResult{
+		Summaries:  []pullpanda.Summary{{Handle: "octocat"}},
+		Failures:   map[string]error{},
+		Unfinished: []string{"hubot"},
+	}
+	err := failuresError(result)
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("failuresError = %v, want an errInterrupted error", err)
+	}
+	if want := "interrupted before fetching hubot"; err.Error() != want {
+		t.Errorf("failuresError = %q, want %q", err, want)
+	}
+
+	result.Unfinished = nil
+	if err := failuresError(result); err != nil {
+		t.Errorf("failuresError = %v for a complete run, want nil", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	found := pullpanda.Summary{Handle: "octocat", Counts: map[string]int{"merged": 3}}
+	empty := pullpanda.Summary{Handle: "octocat", Counts: map[string]int{"merged": 0}}
+	failed := map[string]error{"hubot": errors.New("boom")}
+	tests := []struct {
+		name        string
+		result      pullpanda.RunResult
+		failOnEmpty bool
+		strict      bool
+		warnings    []string
+		want        int
+	}{
+		{name: "all success", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}}, want: exitOK},
+		{name: "empty", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{empty}}, want: exitOK},
+		{name: "empty with fail-on-empty", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{empty}}, failOnEmpty: true, want: exitEmpty},
+		{name: "found with fail-on-empty", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}}, failOnEmpty: true, want: exitOK},
+		{name: "partial failure", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}, Failures: failed}, want: exitFetchFailure},
+		{name: "all failed", result: pullpanda.RunResult{Failures: failed}, failOnEmpty: true, want: exitFetchFailure},
+		{name: "interrupted", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}, Failures: failed, Unfinished: []string{"monalisa"}}, want: exitInterrupted},
+		{name: "warnings", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}}, warnings: []string{"skipped org x"}, want: exitOK},
+		{name: "warnings with strict", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}}, strict: true, warnings: []string{"skipped org x"}, want: exitWarnings},
+		{name: "strict without warnings", result: pullpanda.RunResult{Summaries: []pullpanda.Summary{found}}, strict: true, want: exitOK},
+		{name: "failure before warnings", result: pullpanda.RunResult{Failures: failed}, strict: true, warnings: []string{"skipped org x"}, want: exitFetchFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strict, warnings = tt.strict, tt.warnings
+			defer func() { strict, warnings = false, nil }()
+			if got := exitCode(tt.result, tt.failOnEmpty); got != tt.want {
+				t.Errorf("exitCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTokenFileAuthorizesRequests reads the token from --token-file and
+// checks the requests the client then makes carry it.
+func TestTokenFileAuthorizesRequests(t *testing.T) {
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("  file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	tokenFile, apiURL = path, srv.URL
+	defer func() { token, tokenFile, apiURL = "", "", "" }()
+
+	if err := requireToken(nil); err != nil {
+		t.Fatalf("requireToken failed: %v", err)
+	}
+	if token != "file-token" {
+		t.Fatalf("token = %q, want %q", token, "file-token")
+	}
+	config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}
+	if _, err := newClient().Fetch(context.Background(), config); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(auth) == 0 {
+		t.Fatal("no requests reached the server")
+	}
+	for _, header := range auth {
+		if header != "token file-token" {
+			t.Errorf("Authorization = %q, want %q", header, "token file-token")
+		}
+	}
+}
+
+func TestTokenFileRejectsEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile = path
+	defer func() { token, tokenFile = "", "" }()
+
+	if err := requireToken(nil); err == nil {
+		t.Error("requireToken accepted an empty token file")
+	}
+}
+
+// TestRunReportBreakdownExitCode runs --breakdown weekly with
+// --fail-on-empty, checking a breakdown with no PRs in any bucket fails like
+// the plain report does.
+func TestRunReportBreakdownExitCode(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tt := range []struct {
+		count int
+		want  int
+	}{
+		{count: 0, want: exitEmpty},
+		{count: 2, want: exitOK},
+	} {
+		t.Run(fmt.Sprint(tt.count), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"total_count":%d,"items":[]}`, tt.count)
+			}))
+			defer srv.Close()
+			apiClient = pullpanda.NewClient("test-token")
+			apiClient.BaseURL = srv.URL
+			apiClient.CountOnly = true
+			breakdown, failOnEmpty = "weekly", true
+			startDate, endDate = "2024-01-01", "2024-01-14"
+			outputFile = filepath.Join(t.TempDir(), "breakdown.txt")
+			defer func() {
+				apiClient, breakdown, failOnEmpty = nil, "", false
+				startDate, endDate, outputFile = "", "", ""
+			}()
+
+			config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}
+			if got := runReport(config); got != tt.want {
+				t.Errorf("runReport = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// reportServer points apiClient at a server answering searches with handler,
+// and sends the reports of runReport to a temporary file.
+func reportServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	outputFormat, outputFile = "table", filepath.Join(t.TempDir(), "report.txt")
+	startDate, endDate = "2024-01-01", "2024-01-31"
+	t.Cleanup(func() {
+		srv.Close()
+		apiClient, outputFormat, outputFile = nil, "table", ""
+		startDate, endDate = "", ""
+		warnings, setupWarnings = nil, 0
+	})
+}
+
+// emptySearch answers every search with no results.
+func emptySearch(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+}
+
+// TestStrictFailsOnEveryWarningSource raises each kind of warning and checks
+// --strict turns it into a failure, while the same run passes without it.
+func TestStrictFailsOnEveryWarningSource(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	defer func() { strict = false }()
+	octocat := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}
+
+	t.Run("skipped scope", func(t *testing.T) {
+		reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Query().Get("q"), "repo:octo/missing") {
+				http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+				return
+			}
+			emptySearch(w, r)
+		})
+		config := octocat
+		config.Repos = []string{"octo/api", "octo/missing"}
+		for _, tt := range []struct {
+			strict bool
+			want   int
+		}{{false, exitOK}, {true, exitWarnings}} {
+			strict = tt.strict
+			if got := runReport(config); got != tt.want {
+				t.Errorf("runReport with strict=%v = %d, want %d", tt.strict, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("incomplete results", func(t *testing.T) {
+		reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"total_count":1,"incomplete_results":true,"items":[]}`)
+		})
+		strict = true
+		if got := runReport(octocat); got != exitWarnings {
+			t.Errorf("runReport = %d, want %d", got, exitWarnings)
+		}
+	})
+
+	t.Run("rate-limit wait", func(t *testing.T) {
+		reportServer(t, emptySearch)
+		// Swap in a client whose rate limit is used up
+		rateLimitServer(t, 0)
+		defer func() { warnings = nil }()
+		strict = true
+		if got := runReport(octocat); got != exitOK {
+			t.Fatalf("runReport = %d before waiting, want %d", got, exitOK)
+		}
+		watchDelay(time.Minute)
+		if got := exitCode(pullpanda.RunResult{}, false); got != exitWarnings {
+			t.Errorf("exitCode after waiting for the rate limit = %d, want %d", got, exitWarnings)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("handles: [octocat]\nstatuses: [merged, bogus]\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		strict = true
+		if _, err := configLoader().Load(path); err == nil || !strings.Contains(err.Error(), `"bogus"`) {
+			t.Errorf("Load = %v, want an error naming the bogus status", err)
+		}
+	})
+}
+
+// TestStrictJudgesEachRunByItsOwnWarnings runs a report raising a warning
+// and then a clean one, as batch does, checking only the first fails.
+func TestStrictJudgesEachRunByItsOwnWarnings(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "repo:octo/missing") {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		emptySearch(w, r)
+	})
+	strict = true
+	defer func() { strict = false }()
+
+	warned := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Repos: []string{"octo/api", "octo/missing"}, Statuses: []string{"merged"}}
+	clean := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Repos: []string{"octo/api"}, Statuses: []string{"merged"}}
+	if got := runReport(warned); got != exitWarnings {
+		t.Errorf("first runReport = %d, want %d", got, exitWarnings)
+	}
+	if got := runReport(clean); got != exitOK {
+		t.Errorf("second runReport = %d, want %d, the warnings of the first carried over", got, exitOK)
+	}
+}
+
+// TestInterruptedRunRendersPartialResults cancels a run once its first handle
+// is fetched while the other's search hangs, checking the fetched handle is
+// still rendered, the other is noted as unfinished and the run exits 130.
+func TestInterruptedRunRendersPartialResults(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "author:hubot") {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"total_count":3,"items":[]}`)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := pullpanda.NewClient("test-token")
+	client.BaseURL = srv.URL
+	client.CountOnly = true
+	client.OnProgress = func(done, total int) {
+		if done == 1 {
+			cancel()
+		}
+	}
+	config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}, {Handle: "hubot"}}, Statuses: []string{"merged"}}
+	result, err := client.Fetch(ctx, config)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Fetch = %v, want context.Canceled", err)
+	}
+	if len(result.Summaries) != 1 || result.Summaries[0].Handle != "octocat" {
+		t.Fatalf("summaries = %+v, want octocat only", result.Summaries)
+	}
+	if len(result.Unfinished) != 1 || result.Unfinished[0] != "hubot" {
+		t.Fatalf("unfinished = %v, want hubot", result.Unfinished)
+	}
+
+	var out bytes.Buffer
+	renderReport(&out, "markdown", result, config.Statuses)
+	if !strings.Contains(out.String(), "| octocat | 3 |") {
+		t.Errorf("report doesn't show the fetched handle:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Run interrupted before these handles were fetched: hubot.") {
+		t.Errorf("report doesn't note the unfinished handle:\n%s", out.String())
+	}
+	if code := exitCode(result, false); code != exitInterrupted {
+		t.Errorf("exitCode = %d, want %d", code, exitInterrupted)
+	}
+}
+
+// TestFailedHandlesRenderAlongsideSuccesses fails the searches of one handle
+// and checks the other still gets its row, with the failure noted apart.
+func TestFailedHandlesRenderAlongsideSuccesses(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "author:hubot") {
+			http.Error(w, `{"message":"Validation Failed"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":2,"items":[{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"repository_url":"https://api.github.com/repos/o/r"},{"url":"https://api.github.com/repos/o/r/issues/2","number":2,"repository_url":"https://api.github.com/repos/o/r"}]}`)
+	})
+	outputFormat = "markdown"
+	config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}, {Handle: "hubot"}}, Statuses: []string{"merged"}}
+
+	result := fetchAllPRs(config, pullpanda.DateRange{})
+	if len(result.Summaries) != 1 || result.Summaries[0].Handle != "octocat" {
+		t.Errorf("summaries = %+v, want octocat only", result.Summaries)
+	}
+	if failed := result.FailedHandles(); len(failed) != 1 || failed[0] != "hubot" {
+		t.Errorf("failed handles = %v, want hubot", failed)
+	}
+
+	if code := runReport(config); code != exitFetchFailure {
+		t.Errorf("runReport = %d, want %d", code, exitFetchFailure)
+	}
+	report, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(report), "| octocat | 2 |") {
+		t.Errorf("report lost the handle that succeeded:\n%s", report)
+	}
+	if !strings.Contains(string(report), `| hubot\* | - |`) || !strings.Contains(string(report), `hubot\*: fetching failed: GitHub API returned 422`) {
+		t.Errorf("report doesn't note the failed handle:\n%s", report)
+	}
+}
+
+// TestOutputFileHoldsEachFormat writes the report of one merged PR to
+// --output-file in every format and checks the file is that format.
+func TestOutputFileHoldsEachFormat(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"table", []string{"octocat", "TOTAL"}},
+		{"markdown", []string{"| Handle | merged | Total |", "| octocat | 1 | 1 |"}},
+		{"html", []string{"<html>", " octocat</td><td>1</td>"}},
+		{"jsonl", []string{`{"handle":"octocat",`, `"number":7`}},
+		{"prometheus", []string{"# TYPE pullpanda_prs_total gauge", `pullpanda_prs_total{handle="octocat",status="merged"} 1`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"total_count":1,"items":[{"url":"https://api.github.com/repos/o/r/issues/7","number":7,"title":"fix 7","repository_url":"https://api.github.com/repos/o/r"}]}`)
+			})
+			outputFormat = tt.format
+			if code := runReport(config); code != exitOK {
+				t.Errorf("runReport = %d, want %d", code, exitOK)
+			}
+			report, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(report), want) {
+					t.Errorf("output file lacks %q:\n%s", want, report)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileTitleMatch(t *testing.T) {
+	if re, err := compileTitleMatch(""); re != nil || err != nil {
+		t.Errorf("unset pattern = %v, %v, want nil", re, err)
+	}
+	if re, err := compileTitleMatch(`^fix(\(.+\))?:`); err != nil || !re.MatchString("fix(api): login") || re.MatchString("feat: fix") {
+		t.Errorf("pattern compiled to %v, %v", re, err)
+	}
+	if _, err := compileTitleMatch(`fix(`); err == nil || !strings.Contains(err.Error(), "invalid --title-match pattern") {
+		t.Errorf("err = %v, want an invalid pattern error", err)
+	}
+}
+
+func TestValidatePageSize(t *testing.T) {
+	for size, ok := range map[int]bool{-1: false, 0: false, 1: true, 30: true, 100: true, 101: false} {
+		err := validatePageSize(size)
+		if (err == nil) != ok {
+			t.Errorf("--page-size %d: err = %v, want ok %t", size, err, ok)
+		}
+	}
+}
+
+// TestDatedOutputFile writes the report to an --output-file with {date} in
+// a directory that doesn't exist yet.
+func TestDatedOutputFile(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	reportServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":1,"items":[{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"repository_url":"https://api.github.com/repos/o/r"}]}`)
+	})
+	dir := t.TempDir()
+	outputFormat, outputFile = "markdown", filepath.Join(dir, "reports", "pullpanda-{date}.md")
+	if err := validateOutputFile(outputFile); err != nil {
+		t.Fatal(err)
+	}
+
+	runReport(pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+	report, err := os.ReadFile(filepath.Join(dir, "reports", "pullpanda-2024-01-31.md"))
+	if err != nil {
+		t.Fatalf("report not written under the end date: %v", err)
+	}
+	if !strings.Contains(string(report), "| octocat | 1 |") {
+		t.Errorf("report:\n%s", report)
+	}
+
+	if err := validateOutputFile("report-{week}.md"); err == nil {
+		t.Error("accepted an unknown placeholder")
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// defaultSparklineWeeks is the --sparkline-weeks default.
+const defaultSparklineWeeks = 8
+
+// sparkline renders counts as a string of block characters scaled to the
+// largest count.
+func sparkline(counts []int) string {
+	highest := 0
+	for _, count := range counts {
+		if count > highest {
+			highest = count
+		}
+	}
+
+	var b strings.Builder
+	for _, count := range counts {
+		level := 0
+		if highest > 0 {
+			level = count * (len(sparkBlocks) - 1) / highest
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// sparklineWindow is the last weeks of window, ending on its end date or
+// today when it is open-ended.
+func sparklineWindow(window pullpanda.DateRange, weeks int) pullpanda.DateRange {
+	end := now()
+	if parsed, err := time.Parse("2006-01-02", window.End); err == nil {
+		end = parsed
+	}
+	start := end.AddDate(0, 0, -7*weeks+1)
+	if parsed, err := time.Parse("2006-01-02", window.Start); err == nil && parsed.After(start) {
+		start = parsed
+	}
+	return pullpanda.DateRange{Start: start.Format("2006-01-02"), End: end.Format("2006-01-02"), Location: window.Location}
+}
+
+// addSparklines fetches weekly totals for the handles in result and stores
+// each handle's sparkline in its summary. Handles that failed aren't fetched
+// again, and one whose weeks can't all be fetched gets no sparkline, with a
+// warning, rather than failing the report. An interrupt leaves them all out.
+func addSparklines(config pullpanda.Config, window pullpanda.DateRange, result pullpanda.RunResult) error {
+	buckets, err := breakdownBuckets(sparklineWindow(window, sparklineWeeks), "weekly")
+	if err != nil {
+		return err
+	}
+	fetched := make(map[string]bool)
+	for _, summary := range result.Summaries {
+		fetched[summary.Handle] = true
+	}
+	var handles []pullpanda.Handle
+	for _, handle := range config.Handles {
+		if fetched[handle.Handle] {
+			handles = append(handles, handle)
+		}
+	}
+	config.Handles = handles
+
+	weekly := make(map[string][]int)
+	failed := make(map[string]bool)
+	for _, bucket := range buckets {
+		week := fetchWith(sparklineClient(bucket), config)
+		if len(week.Unfinished) > 0 {
+			return nil
+		}
+		for _, handle := range week.FailedHandles() {
+			failed[handle] = true
+		}
+		for i, total := range summaryTotals(week.Summaries) {
+			handle := week.Summaries[i].Handle
+			weekly[handle] = append(weekly[handle], total)
+		}
+	}
+
+	for i := range result.Summaries {
+		handle := result.Summaries[i].Handle
+		if failed[handle] {
+			warnf("no sparkline for %s, fetching its weekly counts failed", handle)
+			continue
+		}
+		result.Summaries[i].Weekly = weekly[handle]
+	}
+	return nil
+}
+
+// sparklineClient fetches one week of the sparklines: only the PR counts,
+// from the search totals unless a filter needs the PRs themselves, without
+// the extra searches the sparkline doesn't show.
+func sparklineClient(week pullpanda.DateRange) *pullpanda.Client {
+	client := apiClient.WithWindow(week)
+	client.CountOnly = client.CountOnly || !client.CountsNeedPRs()
+	client.IncludeIssues = false
+	client.IncludeReviewComments = false
+	client.IncludeCommits = false
+	client.IncludeCoAuthored = false
+	client.OnPR = nil
+	return client
+}
+
+// supportsUnicode guesses from the locale whether the terminal can show the
+// sparkline's block characters.
+func supportsUnicode() bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return false
+}
+
+// checkSparkline turns --sparkline off with a warning when the table can't
+// show it.
+func checkSparkline() {
+	if showSparkline && outputFormat == "jsonl" {
+		warnf("--sparkline has no effect with --output jsonl")
+		showSparkline = false
+	}
+	if showSparkline && outputFormat == "table" && !supportsUnicode() {
+		warnf("the locale doesn't look like UTF-8, disabling --sparkline")
+		showSparkline = false
+	}
+}
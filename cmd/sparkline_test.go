@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		counts []int
+		want   string
+	}{
+		{counts: []int{0, 1, 2, 3, 4, 5, 6, 7}, want: "▁▂▃▄▅▆▇█"},
+		{counts: []int{3, 12, 0, 6}, want: "▂█▁▄"},
+		{counts: []int{5, 10}, want: "▄█"},
+		{counts: []int{4, 4, 4}, want: "███"},
+		{counts: []int{0, 0, 0}, want: "▁▁▁"},
+		{counts: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.counts); got != tt.want {
+			t.Errorf("sparkline(%v) = %q, want %q", tt.counts, got, tt.want)
+		}
+	}
+}
+
+func TestAddSparklinesSkipsFailedHandles(t *testing.T) {
+	var perPage []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		perPage = append(perPage, r.URL.Query().Get("per_page"))
+		if strings.Contains(q, "author:hubot") {
+			http.Error(w, `{"message":"Validation Failed"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":2,"items":[]}`)
+	}))
+	defer srv.Close()
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	sparklineWeeks = 2
+	defer func() { apiClient, sparklineWeeks, warnings = nil, defaultSparklineWeeks, nil }()
+
+	config := pullpanda.Config{
+		Handles:  []pullpanda.Handle{{Handle: "octocat"}, {Handle: "hubot"}, {Handle: "failed"}},
+		Statuses: []string{"merged"},
+	}
+	result := pullpanda.RunResult{Summaries: []pullpanda.Summary{{Handle: "octocat"}, {Handle: "hubot"}}}
+	window := pullpanda.DateRange{Start: "2024-01-01", End: "2024-01-14"}
+	if err := addSparklines(config, window, result); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := result.Summaries[0].Weekly; len(got) != 2 || got[0] != 2 || got[1] != 2 {
+		t.Errorf("octocat weekly = %v, want [2 2]", got)
+	}
+	if got := result.Summaries[1].Weekly; got != nil {
+		t.Errorf("hubot weekly = %v, want none after its searches failed", got)
+	}
+	for _, p := range perPage {
+		if p != "1" {
+			t.Fatalf("searched with per_page=%s, want count-only searches of one item", p)
+		}
+	}
+	if len(perPage) != 4 {
+		t.Errorf("sent %d searches, want 2 weeks for octocat and hubot, none for the failed handle", len(perPage))
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+
+	"guidewire.com/pullpanda/pullpanda"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the --sqlite table. A run is identified by its date
+// and window, so re-running a report the same day updates its rows, while
+// other days add new ones. Open-ended windows store "" for the missing end.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS pr_counts (
+	run_date   TEXT    NOT NULL,
+	start_date TEXT    NOT NULL,
+	end_date   TEXT    NOT NULL,
+	handle     TEXT    NOT NULL,
+	status     TEXT    NOT NULL,
+	count      INTEGER NOT NULL,
+	PRIMARY KEY (run_date, start_date, end_date, handle, status)
+)`
+
+const sqliteUpsert = `INSERT INTO pr_counts (run_date, start_date, end_date, handle, status, count)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (run_date, start_date, end_date, handle, status) DO UPDATE SET count = excluded.count`
+
+// writeSQLite upserts the count of every handle and status of result into
+// the pr_counts table of the SQLite database at path, creating the file and
+// the table when missing. Failed handles are left out rather than stored as
+// zero.
+func writeSQLite(path string, window pullpanda.DateRange, result pullpanda.RunResult, statuses []string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening SQLite database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("error creating SQLite table: %w", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error writing SQLite database: %w", err)
+	}
+	runDate := now().Format("2006-01-02")
+	for _, summary := range result.Summaries {
+		for _, status := range statuses {
+			if _, err := tx.Exec(sqliteUpsert, runDate, window.Start, window.End, summary.Handle, status, summary.Counts[status]); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error writing SQLite database: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error writing SQLite database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+func TestWriteSQLiteUpsertsRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pullpanda.db")
+	january := pullpanda.DateRange{Start: "2024-01-01", End: "2024-01-31"}
+	run := func(window pullpanda.DateRange, merged, open int) {
+		t.Helper()
+		result := pullpanda.RunResult{
+			Summaries: []pullpanda.Summary{{Handle: "octocat", Counts: map[string]int{"merged": merged, "open": open}}},
+			Failures:  map[string]error{"hubot": errors.New("boom")},
+		}
+		if err := writeSQLite(path, window, result, []string{"merged", "open"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A rerun of the same window updates its rows; another window adds rows
+	run(january, 3, 1)
+	run(january, 4, 0)
+	run(pullpanda.DateRange{Start: "2024-02-01"}, 2, 2)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT run_date, start_date, end_date, handle, status, count FROM pr_counts ORDER BY start_date, status`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got [][]any
+	for rows.Next() {
+		var runDate, start, end, handle, status string
+		var count int
+		if err := rows.Scan(&runDate, &start, &end, &handle, &status, &count); err != nil {
+			t.Fatal(err)
+		}
+		if runDate != now().Format("2006-01-02") {
+			t.Errorf("run_date = %q, want today", runDate)
+		}
+		got = append(got, []any{start, end, handle, status, count})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]any{
+		{"2024-01-01", "2024-01-31", "octocat", "merged", 4},
+		{"2024-01-01", "2024-01-31", "octocat", "open", 0},
+		{"2024-02-01", "", "octocat", "merged", 2},
+		{"2024-02-01", "", "octocat", "open", 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pr_counts = %v, want %v", got, want)
+	}
+}
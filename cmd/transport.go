@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// debugLogger receives the --debug transport logs.
+var debugLogger = log.New(os.Stderr, "[debug] ", log.LstdFlags)
+
+// newHTTPClient builds the client used for all GitHub API calls. Requests go
+// through --proxy when set, otherwise through HTTP_PROXY/HTTPS_PROXY.
+func newHTTPClient() *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = http.ProxyFromEnvironment
+	if proxyURL != nil {
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+	if tlsConfig != nil {
+		base.TLSClientConfig = tlsConfig
+	}
+
+	var transport http.RoundTripper = base
+	if debug {
+		transport = &debugTransport{next: transport}
+	}
+	if retries > 0 {
+		transport = &retryTransport{next: transport, retries: retries}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// proxyURL is the parsed --proxy flag, nil when unset.
+var proxyURL *url.URL
+
+// parseProxy validates the --proxy flag.
+func parseProxy(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q, expected e.g. http://proxy.example.com:8080", proxy)
+	}
+	return u, nil
+}
+
+// tlsConfig is built from --ca-cert and --insecure, nil when neither is set.
+var tlsConfig *tls.Config
+
+// buildTLSConfig adds the CA bundle in caFile to the system roots, or turns
+// off certificate verification altogether with insecure.
+func buildTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	if caFile == "" && !insecure {
+		return nil, nil
+	}
+	config := &tls.Config{}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if insecure {
+		warnf("--insecure is set, TLS certificates are not verified; only use this for testing")
+		config.InsecureSkipVerify = true
+	}
+	return config, nil
+}
+
+// debugTransport logs every request with its status, duration and rate-limit
+// headers. The token is redacted from everything it logs.
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		debugLogger.Printf("%s %s failed after %s: %s", req.Method, redactToken(req.URL.String()), elapsed, redactToken(err.Error()))
+		return resp, err
+	}
+	debugLogger.Printf("%s %s -> %d in %s (rate limit: remaining=%s limit=%s reset=%s)",
+		req.Method, redactToken(req.URL.String()), resp.StatusCode, elapsed,
+		headerOrDash(resp.Header, "X-RateLimit-Remaining"),
+		headerOrDash(resp.Header, "X-RateLimit-Limit"),
+		headerOrDash(resp.Header, "X-RateLimit-Reset"))
+	return resp, nil
+}
+
+// retryBaseDelay is the wait before the first retry, doubled on every
+// following attempt.
+var retryBaseDelay = 500 * time.Millisecond
+
+// retryTransport retries requests answered with a transient 5xx status using
+// exponential backoff with jitter. Rate limiting is left to the callers.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || attempt >= t.retries || !isTransientStatus(resp.StatusCode) || req.Body != nil {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		delay := retryBaseDelay << attempt
+		delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		if enableLog {
+			log.Printf("GitHub returned %d for %s, retrying in %s\n", resp.StatusCode, redactToken(req.URL.String()), delay.Round(time.Millisecond))
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func isTransientStatus(code int) bool {
+	switch code {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// redactToken hides the token and every token of --tokens or the config in
+// s, should one ever end up in a URL or error.
+func redactToken(s string) string {
+	for _, t := range append([]string{token}, apiTokens...) {
+		if t != "" {
+			s = strings.ReplaceAll(s, t, "REDACTED")
+		}
+	}
+	return s
+}
+
+func headerOrDash(h http.Header, key string) string {
+	if v := h.Get(key); v != "" {
+		return v
+	}
+	return "-"
+}
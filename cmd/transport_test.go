@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRedactTokenHidesEveryToken(t *testing.T) {
+	token, apiTokens = "first", []string{"first", "second"}
+	defer func() { token, apiTokens = "", nil }()
+
+	got := redactToken("https://api.example.com/search?access_token=first&t=second")
+	if want := "https://api.example.com/search?access_token=REDACTED&t=REDACTED"; got != want {
+		t.Errorf("redactToken = %q, want %q", got, want)
+	}
+}
+
+func TestRedactTokenWithoutTokens(t *testing.T) {
+	if got := redactToken("https://api.example.com/"); got != "https://api.example.com/" {
+		t.Errorf("redactToken changed %q without tokens", got)
+	}
+}
+
+// TestDebugTransportRedactsTokens sends requests carrying the token through
+// the --debug transport, to a server and to one that's gone, and checks the
+// token never shows up in the log.
+func TestDebugTransportRedactsTokens(t *testing.T) {
+	const secret = "ghp_secret123"
+	token = secret
+	var logged bytes.Buffer
+	debugLogger.SetOutput(&logged)
+	defer func() {
+		token = ""
+		debugLogger.SetOutput(os.Stderr)
+	}()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+	}))
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+	defer srv.Close()
+
+	client := &http.Client{Transport: &debugTransport{next: http.DefaultTransport}}
+	for _, base := range []string{srv.URL, gone.URL} {
+		req, err := http.NewRequest("GET", base+"/search/issues?q=author%3Aoctocat&access_token="+secret, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "token "+secret)
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	out := logged.String()
+	if strings.Contains(out, secret) {
+		t.Errorf("debug log contains the token:\n%s", out)
+	}
+	if strings.Count(out, "access_token=REDACTED") != 2 {
+		t.Errorf("debug log doesn't show both requests redacted:\n%s", out)
+	}
+	if !strings.Contains(out, "remaining=4999") {
+		t.Errorf("debug log is missing the rate-limit headers:\n%s", out)
+	}
+}
+
+// TestProxyFlagRoutesRequests sends a request through a stub proxy set with
+// --proxy and checks the proxy got it for the API host.
+func TestProxyFlagRoutesRequests(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		fmt.Fprint(w, `{}`)
+	}))
+	defer proxy.Close()
+
+	var err error
+	if proxyURL, err = parseProxy(proxy.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { proxyURL = nil }()
+	resp, err := newHTTPClient().Get("http://api.github.invalid/rate_limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://api.github.invalid/rate_limit" {
+		t.Errorf("proxy received %q, want the API request", proxied)
+	}
+
+	for _, bad := range []string{"proxy.example.com:8080", "http://", "::"} {
+		if _, err := parseProxy(bad); err == nil {
+			t.Errorf("parseProxy(%q) accepted an invalid URL", bad)
+		}
+	}
+}
+
+func TestRetryTransportRetriesTransientErrors(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+	tests := []struct {
+		name       string
+		statuses   []int
+		retries    int
+		wantStatus int
+		wantTries  int
+	}{
+		{"503 then 200", []int{503, 200}, 3, 200, 2},
+		{"retries used up", []int{502, 503, 504}, 2, 504, 3},
+		{"not transient", []int{404, 200}, 3, 404, 1},
+	}
+	for _, tt := range tests {
+		var tries int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&tries, 1)
+			w.WriteHeader(tt.statuses[n-1])
+		}))
+		client := &http.Client{Transport: &retryTransport{next: http.DefaultTransport, retries: tt.retries}}
+		resp, err := client.Get(srv.URL)
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus || int(tries) != tt.wantTries {
+			t.Errorf("%s: got %d after %d tries, want %d after %d", tt.name, resp.StatusCode, tries, tt.wantStatus, tt.wantTries)
+		}
+	}
+}
+
+// TestTLSConfigTrustsServer connects to a TLS server with a self-signed
+// certificate, which fails by default and works with its CA given through
+// --ca-cert or with --insecure.
+func TestTLSConfigTrustsServer(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { tlsConfig, warnings = nil, nil }()
+
+	tests := []struct {
+		name     string
+		caFile   string
+		insecure bool
+		wantOK   bool
+	}{
+		{"default", "", false, false},
+		{"ca cert", caFile, false, true},
+		{"insecure", "", true, true},
+	}
+	for _, tt := range tests {
+		var err error
+		if tlsConfig, err = buildTLSConfig(tt.caFile, tt.insecure); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp, err := newHTTPClient().Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != tt.wantOK {
+			t.Errorf("%s: err = %v, want success %v", tt.name, err, tt.wantOK)
+		}
+	}
+
+	if _, err := buildTLSConfig(notPEM, false); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("err = %v, want no PEM certificates found", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for mistakes without querying GitHub",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveConfigFiles(); err != nil {
+			fmt.Printf("  error: %v\n", err)
+			fmt.Println("FAIL")
+			os.Exit(1)
+		}
+		fmt.Printf("Validating %s\n", strings.Join(configFiles, ", "))
+
+		config, err := configLoader().Load(configFiles...)
+		if err != nil {
+			fmt.Printf("  error: %v\n", err)
+			fmt.Println("FAIL")
+			os.Exit(1)
+		}
+
+		errs, warnings := validateConfig(config)
+		for _, e := range errs {
+			fmt.Printf("  error: %s\n", e)
+		}
+		for _, w := range warnings {
+			fmt.Printf("  warning: %s\n", w)
+		}
+
+		if len(errs) > 0 {
+			fmt.Printf("FAIL: %d error(s), %d warning(s)\n", len(errs), len(warnings))
+			os.Exit(1)
+		}
+		fmt.Printf("PASS: %d warning(s)\n", len(warnings))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+// validateConfig returns the hard errors and the warnings found in config.
+func validateConfig(config pullpanda.Config) ([]string, []string) {
+	var errs, warnings []string
+
+	if len(trimHandles(config.Handles)) == 0 {
+		errs = append(errs, "no handles configured")
+	}
+	for i, scope := range config.Scopes {
+		if scope.Org == "" {
+			errs = append(errs, fmt.Sprintf("scope %d has no org", i+1))
+		}
+	}
+	if len(config.Orgs) > 0 && len(config.Repos) > 0 {
+		warnings = append(warnings, "both orgs and repos are set; only orgs are used and repos are ignored")
+	}
+	for _, h := range config.Handles {
+		if len(h.Orgs) > 0 && len(h.Repos) > 0 {
+			warnings = append(warnings, fmt.Sprintf("handle %s sets both orgs and repos; only its orgs are used and its repos are ignored", h.Handle))
+		}
+	}
+
+	return errs, warnings
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestValidateConfig loads config files the way validate does and checks
+// the errors and warnings reported for each.
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		loadErr  bool
+		errs     []string
+		warnings []string
+	}{
+		{
+			name: "valid",
+			yaml: "handles:\n  - octocat\n  - handle: hubot\n    name: Hubot\norgs:\n  - octo\n",
+		},
+		{
+			name:    "malformed yaml",
+			yaml:    "handles: [octocat\n",
+			loadErr: true,
+		},
+		{
+			name: "no handles",
+			yaml: "handles: []\norgs:\n  - octo\n",
+			errs: []string{"no handles configured"},
+		},
+		{
+			name: "scope without org",
+			yaml: "handles:\n  - octocat\nscopes:\n  - org: octo\n  - repos: [api]\n",
+			errs: []string{"scope 2 has no org"},
+		},
+		{
+			name:     "orgs and repos",
+			yaml:     "handles:\n  - octocat\norgs:\n  - octo\nrepos:\n  - octo/api\n",
+			warnings: []string{"both orgs and repos are set; only orgs are used and repos are ignored"},
+		},
+		{
+			name:     "handle with orgs and repos",
+			yaml:     "handles:\n  - handle: octocat\n    orgs: [octo]\n    repos: [octo/api]\n",
+			warnings: []string{"handle octocat sets both orgs and repos; only its orgs are used and its repos are ignored"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := configLoader().Load(path)
+			if tt.loadErr {
+				if err == nil {
+					t.Fatal("Load succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			errs, warnings := validateConfig(config)
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
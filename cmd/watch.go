@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// minWatchInterval keeps --watch from hammering the API.
+const minWatchInterval = 30 * time.Second
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// validateWatch checks --watch against the flags that only make sense for a
+// single run.
+func validateWatch() error {
+	if watchInterval == 0 {
+		return nil
+	}
+	if watchInterval < minWatchInterval {
+		return fmt.Errorf("--watch must be at least %s, got %s", minWatchInterval, watchInterval)
+	}
+	if stepSummary || failOnEmpty || strict || dryRun {
+		return fmt.Errorf("--watch can't be combined with --step-summary, --fail-on-empty, --strict or --dry-run")
+	}
+	return nil
+}
+
+// watch calls render every interval until the process is interrupted,
+// while waiting or while render fetches, clearing the screen first when the
+// report goes to the terminal, and returns exitInterrupted. The same client
+// is reused, so its caches and rate-limit bookkeeping carry over.
+func watch(interval time.Duration, render func() int) int {
+	for {
+		if outputFile == "" && outputFormat != "jsonl" {
+			fmt.Print(clearScreen)
+		}
+		if render() == exitInterrupted {
+			return exitInterrupted
+		}
+		if !waitOrInterrupt(watchDelay(interval)) {
+			return exitInterrupted
+		}
+	}
+}
+
+// watchDelay is the time to wait before the next refresh: interval, or longer
+// when the rate limit is used up and only resets later.
+func watchDelay(interval time.Duration) time.Duration {
+	rateLimit, ok := apiClient.RateLimit()
+	if !ok || rateLimit.Remaining > 0 {
+		return interval
+	}
+	if until := time.Until(rateLimit.Reset); until > interval {
+		warnf("rate limit used up, next refresh once it resets at %s", rateLimit.Reset.Format(time.RFC3339))
+		return until
+	}
+	return interval
+}
+
+// waitOrInterrupt sleeps for d and reports false when interrupted first.
+func waitOrInterrupt(d time.Duration) bool {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"guidewire.com/pullpanda/pullpanda"
+)
+
+// rateLimitServer answers searches with no results and the given remaining
+// rate limit, resetting an hour from now, and counts the requests.
+func rateLimitServer(t *testing.T, remaining int) *int32 {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+	}))
+	apiClient = pullpanda.NewClient("test-token")
+	apiClient.BaseURL = srv.URL
+	t.Cleanup(func() {
+		srv.Close()
+		apiClient = nil
+	})
+	return &requests
+}
+
+func TestWatchRendersEachInterval(t *testing.T) {
+	requests := rateLimitServer(t, 4999)
+	// Keep the screen clearing out of the test output
+	outputFile = os.DevNull
+	defer func() { outputFile = "" }()
+
+	config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}
+	renders := 0
+	render := func() int {
+		renders++
+		fetchAllPRs(config, pullpanda.DateRange{})
+		if renders == 2 {
+			return exitInterrupted
+		}
+		return exitOK
+	}
+
+	const interval = 20 * time.Millisecond
+	start := time.Now()
+	if code := watch(interval, render); code != exitInterrupted {
+		t.Errorf("watch = %d once interrupted, want %d", code, exitInterrupted)
+	}
+	if renders != 2 {
+		t.Errorf("render called %d times, want 2", renders)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("server got %d requests, want 2", got)
+	}
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Errorf("watch refreshed after %s, want at least %s", elapsed, interval)
+	}
+}
+
+func TestWatchDelayWaitsForRateLimitReset(t *testing.T) {
+	defer func() { warnings = nil }()
+	rateLimitServer(t, 0)
+	config := pullpanda.Config{Handles: []pullpanda.Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}}
+	fetchAllPRs(config, pullpanda.DateRange{})
+
+	if delay := watchDelay(time.Minute); delay < 59*time.Minute {
+		t.Errorf("watchDelay = %s with the rate limit used up, want about an hour", delay)
+	}
+}
@@ -0,0 +1,62 @@
+package pullpanda
+
+import (
+	"context"
+	"time"
+)
+
+// activeScopes drops the repository scopes untouched since the window
+// started when SkipInactiveRepos is set. A repository that can't be looked
+// up is kept, so its search reports the problem as usual.
+func (c *Client) activeScopes(ctx context.Context, scopes []searchScope) []searchScope {
+	start, ok := c.Window.startTime()
+	if !c.SkipInactiveRepos || !ok {
+		return scopes
+	}
+	var active []searchScope
+	for _, scope := range scopes {
+		if scope.repo == "" {
+			active = append(active, scope)
+			continue
+		}
+		lastActive, err := c.repoLastActive(ctx, scope.repo)
+		if err != nil {
+			c.logf("Could not check the activity of %s, searching it anyway: %v\n", scope.repo, err)
+			active = append(active, scope)
+			continue
+		}
+		if lastActive.Before(start) {
+			c.logf("Skipping %s, inactive since %s\n", scope.repo, lastActive.Format(time.RFC3339))
+			continue
+		}
+		active = append(active, scope)
+	}
+	return active
+}
+
+// repoLastActive returns the later of the pushed_at and updated_at dates of
+// repo, e.g. "octo/api", looked up once per Client through the repos API.
+func (c *Client) repoLastActive(ctx context.Context, repo string) (time.Time, error) {
+	s := c.state()
+	s.repoActivityMu.Lock()
+	lastActive, ok := s.repoActivity[repo]
+	s.repoActivityMu.Unlock()
+	if ok {
+		return lastActive, nil
+	}
+	var body struct {
+		PushedAt  time.Time `json:"pushed_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	if err := c.fetchJSON(ctx, "/repos/"+repo, &body); err != nil {
+		return time.Time{}, err
+	}
+	lastActive = body.PushedAt
+	if body.UpdatedAt.After(lastActive) {
+		lastActive = body.UpdatedAt
+	}
+	s.repoActivityMu.Lock()
+	s.repoActivity[repo] = lastActive
+	s.repoActivityMu.Unlock()
+	return lastActive, nil
+}
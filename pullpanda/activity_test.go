@@ -0,0 +1,47 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSkipInactiveReposDropsStaleScopes(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/active":
+			fmt.Fprint(w, `{"pushed_at":"2023-12-01T00:00:00Z","updated_at":"2024-01-10T00:00:00Z"}`)
+		case "/repos/octo/stale":
+			fmt.Fprint(w, `{"pushed_at":"2023-06-01T00:00:00Z","updated_at":"2023-07-01T00:00:00Z"}`)
+		case "/search/issues":
+			mu.Lock()
+			queries = append(queries, r.URL.Query().Get("q"))
+			mu.Unlock()
+			fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.Window = DateRange{Start: "2024-01-01"}
+	client.SkipInactiveRepos = true
+	_, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/active", "octo/stale"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "repo:octo/active") {
+		t.Errorf("searched %q, want only octo/active", queries)
+	}
+}
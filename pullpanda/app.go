@@ -0,0 +1,137 @@
+package pullpanda
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AppAuth authenticates as a GitHub App installation. Its Token method mints
+// a JWT signed with the app's private key, exchanges it for an installation
+// token and reuses that until shortly before it expires. Use it as
+// Client.TokenSource.
+type AppAuth struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     *rsa.PrivateKey
+	// BaseURL, HTTPClient and UserAgent default to DefaultBaseURL,
+	// http.DefaultClient and DefaultUserAgent, and should match the Client's.
+	BaseURL    string
+	HTTPClient *http.Client
+	UserAgent  string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// appTokenMargin is how long before its expiry an installation token is
+// replaced, so requests in flight never carry an expired one.
+const appTokenMargin = 5 * time.Minute
+
+// ParseAppPrivateKey decodes the PEM private key GitHub generates for an
+// app, in PKCS #1 or PKCS #8 form.
+func ParseAppPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in the private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Token returns a valid installation token, fetching a new one when there
+// is none yet or the current one is about to expire.
+func (a *AppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expires) > appTokenMargin {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return "", err
+	}
+	baseURL, httpClient := a.BaseURL, a.HTTPClient
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", a.InstallationID)
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(baseURL, "/")+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", userAgent(a.UserAgent))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting installation token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("error requesting installation token: %w", newAPIError(resp, ""))
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding installation token: %w", err)
+	}
+	a.token, a.expires = body.Token, body.ExpiresAt
+	return a.token, nil
+}
+
+// jwt returns the RS256 token identifying the app. It is backdated a minute
+// against clock drift and valid for the nine minutes after, below GitHub's
+// ten minute limit.
+func (a *AppAuth) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing app JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
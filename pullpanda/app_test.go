@@ -0,0 +1,112 @@
+package pullpanda
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestAppAuthExchangesJWTForInstallationToken serves the installation token
+// endpoint, checking the JWT it's sent is signed by the app's key, and
+// checks the token is reused until it nears its expiry.
+func TestAppAuthExchangesJWTForInstallationToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	expiresIn := time.Hour
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if r.Method != "POST" || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			t.Errorf("Authorization = %q, want a bearer JWT", r.Header.Get("Authorization"))
+		}
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			t.Errorf("malformed JWT %q", jwt)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("JWT signature doesn't verify: %v", err)
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var body struct{ Iss int64 }
+		if err := json.Unmarshal(claims, &body); err != nil || body.Iss != 7 {
+			t.Errorf("JWT claims %s, want iss 7", claims)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, n, time.Now().Add(expiresIn).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	auth := &AppAuth{AppID: 7, InstallationID: 42, PrivateKey: key, BaseURL: srv.URL}
+	for i := 0; i < 2; i++ {
+		token, err := auth.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "ghs_1" {
+			t.Errorf("token = %q, want the first one reused", token)
+		}
+	}
+
+	// A token about to expire is replaced
+	expiresIn = time.Minute
+	auth = &AppAuth{AppID: 7, InstallationID: 42, PrivateKey: key, BaseURL: srv.URL}
+	first, _ := auth.Token(context.Background())
+	second, err := auth.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("token %q was reused a minute before its expiry", first)
+	}
+
+	auth = &AppAuth{AppID: 7, InstallationID: 99, PrivateKey: key, BaseURL: srv.URL}
+	if _, err := auth.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "error requesting installation token") {
+		t.Errorf("err = %v, want the failed request reported", err)
+	}
+}
+
+func TestParseAppPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{
+		"PKCS #1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"PKCS #8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		parsed, err := ParseAppPrivateKey(pem.EncodeToMemory(block))
+		if err != nil || !parsed.Equal(key) {
+			t.Errorf("%s: parsed %v, %v", name, parsed != nil, err)
+		}
+	}
+	if _, err := ParseAppPrivateKey([]byte("not a key")); err == nil {
+		t.Error("parsed a key from non-PEM data")
+	}
+}
@@ -0,0 +1,44 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+type avatarLookup struct {
+	url string
+	err error
+}
+
+// AvatarURL returns the avatar of a GitHub handle, looked up once per Client
+// through the users API.
+func (c *Client) AvatarURL(ctx context.Context, handle string) (string, error) {
+	s := c.state()
+	s.avatarMu.Lock()
+	cached, ok := s.avatars[handle]
+	s.avatarMu.Unlock()
+	if ok {
+		return cached.url, cached.err
+	}
+
+	// Fetched unlocked so one slow handle doesn't hold up the others
+	avatar, err := c.fetchAvatarURL(ctx, handle)
+	s.avatarMu.Lock()
+	s.avatars[handle] = avatarLookup{avatar, err}
+	s.avatarMu.Unlock()
+	return avatar, err
+}
+
+func (c *Client) fetchAvatarURL(ctx context.Context, handle string) (string, error) {
+	var user struct {
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := c.fetchJSON(ctx, "/users/"+url.PathEscape(handle), &user); err != nil {
+		return "", err
+	}
+	if user.AvatarURL == "" {
+		return "", fmt.Errorf("no avatar_url in response")
+	}
+	return user.AvatarURL, nil
+}
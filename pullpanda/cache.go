@@ -0,0 +1,66 @@
+package pullpanda
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cachePath is the file caching the search page at searchPath. The path
+// carries the query, page and per_page, so every page is cached on its own
+// and a run that pages further, e.g. after raising Limit, reuses the pages
+// it already has and only fetches the rest.
+func (c *Client) cachePath(searchPath string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(c.BaseURL, "/") + searchPath))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// cachedPage returns the cached response body for searchPath, if CacheDir is
+// set and it holds one younger than CacheTTL.
+func (c *Client) cachedPage(searchPath string) ([]byte, bool) {
+	if c.CacheDir == "" {
+		return nil, false
+	}
+	path := c.cachePath(searchPath)
+	info, err := os.Stat(path)
+	if err != nil || (c.CacheTTL > 0 && time.Since(info.ModTime()) > c.CacheTTL) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// storePage caches the response body for searchPath when CacheDir is set.
+// The file is written under a temporary name and renamed, so concurrent runs
+// never read a partial page. Failures only cost the cache hit.
+func (c *Client) storePage(searchPath string, data []byte) {
+	if c.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.CacheDir, 0o700); err != nil {
+		c.logf("Error creating cache directory: %v\n", err)
+		return
+	}
+	tmp, err := os.CreateTemp(c.CacheDir, "page-*.tmp")
+	if err != nil {
+		c.logf("Error caching search page: %v\n", err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.cachePath(searchPath))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		c.logf("Error caching search page: %v\n", err)
+	}
+}
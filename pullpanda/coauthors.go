@@ -0,0 +1,130 @@
+package pullpanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// coAuthorTrailer matches a Co-authored-by trailer of a commit message,
+// capturing the name and email.
+var coAuthorTrailer = regexp.MustCompile(`(?im)^co-authored-by:\s*(.*?)\s*<([^>]*)>\s*$`)
+
+// coAuthor is a person credited by a Co-authored-by trailer.
+type coAuthor struct {
+	name  string
+	email string
+}
+
+// errCoAuthoredUnscoped is returned when IncludeCoAuthored has no orgs or
+// repos to search, since every merged PR on GitHub would be listed.
+var errCoAuthoredUnscoped = errors.New("co-authored PRs are only searched within orgs or repos; configure orgs, repos or scopes")
+
+// coAuthoredQuery searches the merged PRs of others in the window, whose
+// commits may credit handle as a co-author.
+func (c *Client) coAuthoredQuery(handle string, config Config) string {
+	return fmt.Sprintf("is:pr is:merged -author:%s", handle) + c.Window.FieldQualifiers("merged") + c.searchFilters() + authorExclusions(config.ExcludeAuthors)
+}
+
+// countCoAuthored returns the number of merged PRs in scope with a commit
+// crediting summary's handle in a Co-authored-by trailer. It lists every
+// merged PR of the scope and the commits of each, cached across handles.
+func (c *Client) countCoAuthored(ctx context.Context, summary *Summary, query string, scope searchScope) (int, error) {
+	if scope.qualifier == "" {
+		return 0, errCoAuthoredUnscoped
+	}
+	path := issuesSearch + "?q=" + url.QueryEscape(query+scope.qualifier)
+	c.logf("Fetching co-authored PRs for %s%s with query: %s\n", summary.Handle, scope.description, c.BaseURL+path)
+
+	result, err := c.searchPages(ctx, path, 0)
+	if err != nil {
+		return 0, err
+	}
+	summary.Incomplete = summary.Incomplete || result.IncompleteResults
+
+	count := 0
+	for _, pr := range result.Items {
+		if pr.Repository == "" || pr.Number == 0 {
+			return 0, fmt.Errorf("unrecognized pull request URL %q", pr.URL)
+		}
+		coAuthors, err := c.prCoAuthors(ctx, pr.Repository, pr.Number)
+		if err != nil {
+			return 0, fmt.Errorf("error listing the commits of %s#%d: %w", pr.Repository, pr.Number, err)
+		}
+		for _, coAuthor := range coAuthors {
+			if coAuthor.is(summary.Handle) {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+// prCoAuthors returns the co-authors named in the commit messages of a PR,
+// caching them since every handle looks at the same PRs. GitHub lists up to
+// 250 commits of a PR.
+func (c *Client) prCoAuthors(ctx context.Context, repo string, number int) ([]coAuthor, error) {
+	key := fmt.Sprintf("%s#%d", repo, number)
+
+	s := c.state()
+	s.coAuthorsMu.Lock()
+	cached, ok := s.coAuthors[key]
+	s.coAuthorsMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var coAuthors []coAuthor
+	for page := 1; ; page++ {
+		var result []struct {
+			Commit struct {
+				Message string `json:"message"`
+			} `json:"commit"`
+		}
+		commitsPath := fmt.Sprintf("/repos/%s/pulls/%d/commits?per_page=100&page=%d", repo, number, page)
+		if err := c.fetchJSON(ctx, commitsPath, &result); err != nil {
+			return nil, err
+		}
+		for _, commit := range result {
+			coAuthors = append(coAuthors, parseCoAuthors(commit.Commit.Message)...)
+		}
+		if len(result) < 100 {
+			break
+		}
+	}
+
+	s.coAuthorsMu.Lock()
+	s.coAuthors[key] = coAuthors
+	s.coAuthorsMu.Unlock()
+	return coAuthors, nil
+}
+
+func parseCoAuthors(message string) []coAuthor {
+	var coAuthors []coAuthor
+	for _, match := range coAuthorTrailer.FindAllStringSubmatch(message, -1) {
+		coAuthors = append(coAuthors, coAuthor{name: match[1], email: strings.ToLower(match[2])})
+	}
+	return coAuthors
+}
+
+// is reports whether the co-author is handle. Trailers carry a name and an
+// email rather than a login, so it matches GitHub's noreply addresses,
+// handle@users.noreply.github.com and ID+handle@users.noreply.github.com,
+// and a name equal to the handle, ignoring case.
+func (a coAuthor) is(handle string) bool {
+	handle = strings.ToLower(handle)
+	local, domain, ok := strings.Cut(a.email, "@")
+	if ok && domain == "users.noreply.github.com" {
+		if _, login, ok := strings.Cut(local, "+"); ok {
+			local = login
+		}
+		if local == handle {
+			return true
+		}
+	}
+	return strings.ToLower(a.name) == handle
+}
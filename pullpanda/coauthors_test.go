@@ -0,0 +1,88 @@
+package pullpanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIncludeCoAuthoredReadsTrailers(t *testing.T) {
+	commits := map[string][]string{
+		"/repos/octo/api/pulls/10/commits": {
+			"Fix the login redirect\n\nCo-authored-by: Octo Cat <583231+OctoCat@users.noreply.github.com>",
+		},
+		"/repos/octo/api/pulls/11/commits": {
+			"Add the cache\n\nCo-authored-by: Hubot <hubot@example.com>",
+			"Tidy up\n\nSigned-off-by: Mona <mona@example.com>\nco-authored-by: octocat <octo@example.com>",
+		},
+		"/repos/octo/api/pulls/12/commits": {
+			"Bump deps\n\nCo-authored-by: octocat-bot <bot@example.com>",
+		},
+	}
+	var mu sync.Mutex
+	listed := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search/issues" {
+			if !strings.Contains(r.URL.Query().Get("q"), "-author:") {
+				fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+				return
+			}
+			var items []string
+			for _, n := range []int{10, 11, 12} {
+				items = append(items, fmt.Sprintf(`{"url":"https://api.github.com/repos/octo/api/issues/%[1]d","number":%[1]d,"repository_url":"https://api.github.com/repos/octo/api"}`, n))
+			}
+			fmt.Fprintf(w, `{"total_count":3,"items":[%s]}`, strings.Join(items, ","))
+			return
+		}
+		messages, ok := commits[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		listed[r.URL.Path]++
+		mu.Unlock()
+		var result []map[string]map[string]string
+		for _, message := range messages {
+			result = append(result, map[string]map[string]string{"commit": {"message": message}})
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.IncludeCoAuthored = true
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}, {Handle: "hubot"}, {Handle: "monalisa"}},
+		Repos:    []string{"octo/api"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"octocat": 2, "hubot": 1, "monalisa": 0}
+	for _, summary := range result.Summaries {
+		if summary.CoAuthored != want[summary.Handle] {
+			t.Errorf("%s co-authored %d PRs, want %d", summary.Handle, summary.CoAuthored, want[summary.Handle])
+		}
+	}
+
+	// The commits are cached, so a later fetch doesn't list them again
+	clear(listed)
+	result, err = client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/api"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Summaries[0].CoAuthored != 2 || len(listed) != 0 {
+		t.Errorf("refetch co-authored %d PRs listing %v, want 2 from the cache", result.Summaries[0].CoAuthored, listed)
+	}
+}
@@ -0,0 +1,260 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Counting PRs by CODEOWNERS scope is a multi-step resolution:
+//
+//  1. For every repository a PR belongs to, fetch its CODEOWNERS file from one
+//     of the locations GitHub recognizes and collect the rules.
+//  2. Fetch the files changed by the PR through the pull request files API.
+//  3. Keep the PR if any changed file is owned by the requested owner, using
+//     GitHub's "last matching rule wins" semantics.
+//
+// CODEOWNERS rules are cached per repository and changed files per PR for the
+// lifetime of the Client, so handles sharing repositories don't refetch them.
+
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// filterByCodeowners applies the CODEOWNERS scope when CodeownersTeam is set
+// and returns prs untouched otherwise.
+func (c *Client) filterByCodeowners(ctx context.Context, prs []PullRequest) ([]PullRequest, error) {
+	if c.CodeownersTeam == "" {
+		return prs, nil
+	}
+	owned, err := c.filterOwnedPRs(ctx, prs)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving CODEOWNERS scope: %w", err)
+	}
+	return owned, nil
+}
+
+// filterOwnedPRs keeps only the PRs touching at least one path owned by
+// CodeownersTeam.
+func (c *Client) filterOwnedPRs(ctx context.Context, prs []PullRequest) ([]PullRequest, error) {
+	var owned []PullRequest
+	for _, pr := range prs {
+		repo, number := pr.Repository, pr.Number
+		if repo == "" || number == 0 {
+			return nil, fmt.Errorf("unrecognized pull request URL %q", pr.URL)
+		}
+		rules, err := c.repoCodeowners(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		files, err := c.prFiles(ctx, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if ownedBy(rules, file, c.CodeownersTeam) {
+				owned = append(owned, pr)
+				break
+			}
+		}
+	}
+	return owned, nil
+}
+
+// parsePRURL extracts "owner/name" and the PR number from the API or web URL
+// of a pull request.
+func parsePRURL(prURL string) (string, int, error) {
+	u, err := url.Parse(prURL)
+	if err != nil {
+		return "", 0, err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	// API URLs have a /repos/ segment, after /api/v3 on GitHub Enterprise
+	for i, part := range parts {
+		if part == "repos" {
+			parts = parts[i+1:]
+			break
+		}
+	}
+	if len(parts) != 4 {
+		return "", 0, fmt.Errorf("unrecognized pull request URL %q", prURL)
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return "", 0, fmt.Errorf("unrecognized pull request URL %q", prURL)
+	}
+	return parts[0] + "/" + parts[1], number, nil
+}
+
+func (c *Client) repoCodeowners(ctx context.Context, repo string) ([]codeownersRule, error) {
+	s := c.state()
+	s.codeownersMu.Lock()
+	rules, ok := s.codeowners[repo]
+	s.codeownersMu.Unlock()
+	if ok {
+		return rules, nil
+	}
+
+	for _, path := range codeownersPaths {
+		content, found, err := c.fetchRawFile(ctx, repo, path)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			rules = parseCodeowners(content)
+			break
+		}
+	}
+	if rules == nil {
+		c.logf("No CODEOWNERS file found in %s\n", repo)
+	}
+	s.codeownersMu.Lock()
+	s.codeowners[repo] = rules
+	s.codeownersMu.Unlock()
+	return rules, nil
+}
+
+// fetchRawFile reads a file from a repository's default branch. A missing
+// file is reported through found rather than as an error.
+func (c *Client) fetchRawFile(ctx context.Context, repo, path string) (string, bool, error) {
+	req, err := c.newRequest(ctx, fmt.Sprintf("/repos/%s/contents/%s", repo, path))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", c.acceptHeader("application/vnd.github.raw+json"))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, newAPIError(resp, "")
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, err
+	}
+	return string(body), true, nil
+}
+
+func (c *Client) prFiles(ctx context.Context, repo string, number int) ([]string, error) {
+	key := fmt.Sprintf("%s#%d", repo, number)
+
+	s := c.state()
+	s.prFilesMu.Lock()
+	cached, ok := s.prFiles[key]
+	s.prFilesMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var files []string
+	for page := 1; ; page++ {
+		var result []struct {
+			Filename string `json:"filename"`
+		}
+		filesPath := fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=100&page=%d", repo, number, page)
+		if err := c.fetchJSON(ctx, filesPath, &result); err != nil {
+			return nil, err
+		}
+		for _, file := range result {
+			files = append(files, file.Filename)
+		}
+		if len(result) < 100 {
+			break
+		}
+	}
+
+	s.prFilesMu.Lock()
+	s.prFiles[key] = files
+	s.prFilesMu.Unlock()
+	return files, nil
+}
+
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, codeownersRule{
+			pattern: codeownersPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+	return rules
+}
+
+// codeownersPattern translates a gitignore-style CODEOWNERS pattern into a
+// regular expression matched against repository-relative paths.
+func codeownersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				expr.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	prefix := "^"
+	if !anchored {
+		prefix = "^(.*/)?"
+	}
+	suffix := "(/.*)?$"
+	if dirOnly {
+		suffix = "/.*$"
+	}
+	return regexp.MustCompile(prefix + expr.String() + suffix)
+}
+
+// ownedBy reports whether the last rule matching path lists owner.
+func ownedBy(rules []codeownersRule, path, owner string) bool {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if !rules[i].pattern.MatchString(path) {
+			continue
+		}
+		for _, o := range rules[i].owners {
+			if strings.EqualFold(o, owner) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
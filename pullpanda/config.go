@@ -0,0 +1,313 @@
+package pullpanda
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config lists the handles to report on, where to search and which PR
+// statuses to count.
+type Config struct {
+	Handles  []Handle `yaml:"handles"`
+	Orgs     []string `yaml:"orgs"`
+	Repos    []string `yaml:"repos"`
+	Scopes   []Scope  `yaml:"scopes"`
+	Statuses []string `yaml:"statuses"`
+	// ExcludeAuthors are left out of every search with -author:, e.g.
+	// "app/dependabot" for a GitHub App.
+	ExcludeAuthors []string `yaml:"excludeAuthors"`
+	// DateFields overrides, per status, which date the range is matched on,
+	// e.g. {closed: closed} to count PRs closed rather than opened in it.
+	DateFields map[string]string `yaml:"dateField"`
+	// Tokens are GitHub tokens to use in turn, see Client.Tokens. Fetch
+	// doesn't read them; they're only carried for the caller.
+	Tokens []string `yaml:"tokens"`
+}
+
+// DateFields are the PR dates a range can be matched on.
+var DateFields = []string{"created", "updated", "closed", "merged"}
+
+// DateField returns the date status is matched on: the configured override,
+// or merged for merged PRs and created for everything else.
+func (c Config) DateField(status string) string {
+	if field := c.DateFields[status]; field != "" {
+		return field
+	}
+	return defaultDateField(status)
+}
+
+// Scope limits queries to some repos of an org, or to the whole org when no
+// repos are listed. Repos may be given with or without the org prefix.
+type Scope struct {
+	Org   string   `yaml:"org"`
+	Repos []string `yaml:"repos"`
+}
+
+// Handle is a GitHub handle with an optional display name. In the config it
+// can be written either as a plain string or as a {handle, name} map. Orgs
+// and Repos, when either is set, replace the config's orgs, repos and scopes
+// for this handle's searches.
+type Handle struct {
+	Handle string   `yaml:"handle"`
+	Name   string   `yaml:"name"`
+	Orgs   []string `yaml:"orgs"`
+	Repos  []string `yaml:"repos"`
+}
+
+// forHandle returns c with the scoping overrides of h applied.
+func (c Config) forHandle(h Handle) Config {
+	if len(h.Orgs) > 0 || len(h.Repos) > 0 {
+		c.Orgs, c.Repos, c.Scopes = h.Orgs, h.Repos, nil
+	}
+	return c
+}
+
+func (h *Handle) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var plain string
+	if err := unmarshal(&plain); err == nil {
+		h.Handle = plain
+		return nil
+	}
+
+	type rawHandle Handle
+	var raw rawHandle
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if raw.Handle == "" {
+		return fmt.Errorf("handle entry %+v is missing the handle field", raw)
+	}
+	*h = Handle(raw)
+	return nil
+}
+
+// KnownStatuses are the PR states GitHub search understands with is:.
+var KnownStatuses = []string{"open", "closed", "merged"}
+
+// LoadConfig reads one or more YAML config files and merges them in order
+// with Merge, which also drops duplicate entries such as a status listed
+// twice. Statuses default to merged when no file sets them. ${VAR} and $VAR
+// references in the values are expanded from the environment, with
+// undefined variables expanding to "".
+func LoadConfig(configFiles ...string) (Config, error) {
+	return ConfigLoader{}.Load(configFiles...)
+}
+
+// ConfigLoader loads config files like LoadConfig, with options.
+type ConfigLoader struct {
+	// StrictEnv fails the load when a value references an undefined
+	// environment variable, instead of expanding it to "".
+	StrictEnv bool
+	// Stdin is read for a config file named "-", os.Stdin when nil.
+	Stdin io.Reader
+}
+
+// StdinConfig is the config file name that reads the config from stdin.
+const StdinConfig = "-"
+
+// Load reads and merges configFiles as described for LoadConfig. A file
+// named StdinConfig is read from Stdin, which can only be done once.
+func (l ConfigLoader) Load(configFiles ...string) (Config, error) {
+	var config Config
+
+	readStdin := false
+	for _, configFile := range configFiles {
+		var file []byte
+		var err error
+		if configFile == StdinConfig {
+			if readStdin {
+				return config, fmt.Errorf("the config can only be read from stdin once")
+			}
+			readStdin = true
+			stdin := l.Stdin
+			if stdin == nil {
+				stdin = os.Stdin
+			}
+			file, err = io.ReadAll(stdin)
+			configFile = "from stdin"
+		} else {
+			file, err = ioutil.ReadFile(configFile)
+		}
+		if err != nil {
+			return config, fmt.Errorf("error reading config file: %w", err)
+		}
+
+		var loaded Config
+		if err := yaml.Unmarshal(file, &loaded); err != nil {
+			return config, fmt.Errorf("error parsing config file %s: %w", configFile, err)
+		}
+		if missing := loaded.expandEnv(); l.StrictEnv && len(missing) > 0 {
+			return config, fmt.Errorf("config file %s references undefined environment variables: %s", configFile, strings.Join(missing, ", "))
+		}
+		config = config.Merge(loaded)
+	}
+
+	// Set default statuses if not provided
+	if len(config.Statuses) == 0 {
+		config.Statuses = []string{"merged"}
+	}
+	var invalid []string
+	for _, status := range config.Statuses {
+		if !contains(KnownStatuses, status) {
+			invalid = append(invalid, fmt.Sprintf("%q", status))
+		}
+	}
+	if len(invalid) > 0 {
+		return config, fmt.Errorf("unknown statuses in config: %s; valid statuses are %s", strings.Join(invalid, ", "), strings.Join(KnownStatuses, ", "))
+	}
+	var overridden []string
+	for status := range config.DateFields {
+		overridden = append(overridden, status)
+	}
+	sort.Strings(overridden)
+	for _, status := range overridden {
+		field := config.DateFields[status]
+		if !contains(KnownStatuses, status) {
+			return config, fmt.Errorf("unknown status %q in dateField; valid statuses are %s", status, strings.Join(KnownStatuses, ", "))
+		}
+		if !contains(DateFields, field) {
+			return config, fmt.Errorf("unknown date field %q for %s in dateField; valid fields are %s", field, status, strings.Join(DateFields, ", "))
+		}
+	}
+
+	return config, nil
+}
+
+// expandEnv expands environment variable references in every string value of
+// c in place, and returns the names of the variables that aren't defined.
+// List entries left empty, such as an org from an unset variable, are dropped.
+func (c *Config) expandEnv() []string {
+	var missing []string
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok && !contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+	expandAll := func(list []string) []string {
+		var expanded []string
+		for _, v := range list {
+			if v = expand(v); v != "" {
+				expanded = append(expanded, v)
+			}
+		}
+		return expanded
+	}
+
+	for i := range c.Handles {
+		c.Handles[i].Handle = expand(c.Handles[i].Handle)
+		c.Handles[i].Name = expand(c.Handles[i].Name)
+		c.Handles[i].Orgs = expandAll(c.Handles[i].Orgs)
+		c.Handles[i].Repos = expandAll(c.Handles[i].Repos)
+	}
+	c.Orgs = expandAll(c.Orgs)
+	c.Repos = expandAll(c.Repos)
+	for i := range c.Scopes {
+		c.Scopes[i].Org = expand(c.Scopes[i].Org)
+		c.Scopes[i].Repos = expandAll(c.Scopes[i].Repos)
+	}
+	c.Statuses = expandAll(c.Statuses)
+	c.ExcludeAuthors = expandAll(c.ExcludeAuthors)
+	c.Tokens = expandAll(c.Tokens)
+	for status, field := range c.DateFields {
+		c.DateFields[status] = expand(field)
+	}
+	return missing
+}
+
+// Merge returns c with the lists of other appended, skipping entries c
+// already has. A handle listed in both keeps its position in c but takes the
+// name and the orgs and repos from other when other sets them, and scopes of
+// the same org are merged into one.
+func (c Config) Merge(other Config) Config {
+	return Config{
+		Handles:  mergeHandles(c.Handles, other.Handles),
+		Orgs:     mergeLists(c.Orgs, other.Orgs),
+		Repos:    mergeLists(c.Repos, other.Repos),
+		Scopes:   mergeScopes(c.Scopes, other.Scopes),
+		Statuses: mergeLists(c.Statuses, other.Statuses),
+
+		ExcludeAuthors: mergeLists(c.ExcludeAuthors, other.ExcludeAuthors),
+		DateFields:     mergeDateFields(c.DateFields, other.DateFields),
+		Tokens:         mergeLists(c.Tokens, other.Tokens),
+	}
+}
+
+// mergeDateFields returns the overrides of a and b, b winning for a status
+// set in both.
+func mergeDateFields(a, b map[string]string) map[string]string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	merged := make(map[string]string)
+	for status, field := range a {
+		merged[status] = field
+	}
+	for status, field := range b {
+		merged[status] = field
+	}
+	return merged
+}
+
+// mergeLists appends the entries of b missing from a, keeping order.
+func mergeLists(a, b []string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, v := range append(append([]string{}, a...), b...) {
+		if !seen[v] {
+			seen[v] = true
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+func mergeHandles(a, b []Handle) []Handle {
+	index := make(map[string]int)
+	var merged []Handle
+	for _, h := range append(append([]Handle{}, a...), b...) {
+		i, ok := index[h.Handle]
+		if !ok {
+			index[h.Handle] = len(merged)
+			merged = append(merged, h)
+			continue
+		}
+		if h.Name != "" {
+			merged[i].Name = h.Name
+		}
+		if len(h.Orgs) > 0 || len(h.Repos) > 0 {
+			merged[i].Orgs, merged[i].Repos = h.Orgs, h.Repos
+		}
+	}
+	return merged
+}
+
+// mergeScopes combines scopes of the same org. A scope covering the whole
+// org absorbs any repo list for it.
+func mergeScopes(a, b []Scope) []Scope {
+	index := make(map[string]int)
+	var merged []Scope
+	for _, scope := range append(append([]Scope{}, a...), b...) {
+		i, ok := index[scope.Org]
+		if !ok {
+			index[scope.Org] = len(merged)
+			merged = append(merged, scope)
+			continue
+		}
+		if len(merged[i].Repos) == 0 || len(scope.Repos) == 0 {
+			merged[i].Repos = nil
+		} else {
+			merged[i].Repos = mergeLists(merged[i].Repos, scope.Repos)
+		}
+	}
+	return merged
+}
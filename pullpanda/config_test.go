@@ -0,0 +1,295 @@
+package pullpanda
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// loadYAML loads a config given inline, as if read from stdin.
+func loadYAML(t *testing.T, yaml string) (Config, error) {
+	t.Helper()
+	return ConfigLoader{Stdin: strings.NewReader(yaml)}.Load(StdinConfig)
+}
+
+func TestLoadConfigHandleForms(t *testing.T) {
+	config, err := loadYAML(t, `
+handles:
+  - octocat
+  - handle: hubot
+    name: Hu Bot
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Handle{{Handle: "octocat"}, {Handle: "hubot", Name: "Hu Bot"}}
+	if !reflect.DeepEqual(config.Handles, want) {
+		t.Errorf("handles = %+v, want %+v", config.Handles, want)
+	}
+}
+
+func TestLoadConfigRejectsHandleWithoutHandle(t *testing.T) {
+	_, err := loadYAML(t, `
+handles:
+  - name: Nobody
+`)
+	if err == nil || !strings.Contains(err.Error(), "missing the handle field") {
+		t.Errorf("err = %v, want a missing handle error", err)
+	}
+}
+
+func TestFetchLabelsRowsByName(t *testing.T) {
+	srv, queries := searchServer(t, "")
+	result, err := testClient(srv.URL).Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}, {Handle: "hubot", Name: "Hu Bot"}},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, summary := range result.Summaries {
+		labels = append(labels, summary.Label())
+	}
+	if want := []string{"octocat", "Hu Bot"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+	for _, q := range queries() {
+		if strings.Contains(q, "Hu Bot") {
+			t.Errorf("searched by name: %q", q)
+		}
+	}
+}
+
+// TestLoadConfigScopeShapes loads the flat orgs/repos shape, the nested
+// scopes shape sharing a repo list through a YAML anchor, and both at once,
+// and checks the scopes they are searched in.
+func TestLoadConfigScopeShapes(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "flat repos",
+			yaml: `
+repos: [octo/api, hub/cli]
+`,
+			want: []string{" repo:octo/api", " repo:hub/cli"},
+		},
+		{
+			name: "flat orgs over repos",
+			yaml: `
+orgs: [octo]
+repos: [hub/cli]
+`,
+			want: []string{" org:octo"},
+		},
+		{
+			name: "nested with anchors",
+			yaml: `
+scopes:
+  - org: octo
+    repos: &shared
+      - api
+      - web
+  - org: hub
+    repos: *shared
+  - org: whole
+`,
+			want: []string{
+				" org:octo repo:octo/api", " org:octo repo:octo/web",
+				" org:hub repo:hub/api", " org:hub repo:hub/web",
+				" org:whole",
+			},
+		},
+		{
+			name: "flat and nested",
+			yaml: `
+orgs: [octo]
+scopes:
+  - org: hub
+    repos: [hub/cli]
+`,
+			want: []string{" org:octo", " org:hub repo:hub/cli"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadYAML(t, "handles: [octocat]\n"+tt.yaml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, scope := range searchScopes(config.Orgs, config.Repos, config.Scopes) {
+				got = append(got, scope.qualifier)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scopes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeUnionsConfigs(t *testing.T) {
+	a := Config{
+		Handles:  []Handle{{Handle: "octocat"}, {Handle: "hubot", Name: "Hubot"}},
+		Orgs:     []string{"octo"},
+		Scopes:   []Scope{{Org: "hub", Repos: []string{"cli"}}, {Org: "whole"}},
+		Statuses: []string{"merged"},
+	}
+	b := Config{
+		Handles:  []Handle{{Handle: "hubot", Name: "Hu Bot"}, {Handle: "monalisa"}},
+		Orgs:     []string{"octo", "github"},
+		Scopes:   []Scope{{Org: "hub", Repos: []string{"cli", "web"}}, {Org: "whole", Repos: []string{"api"}}},
+		Statuses: []string{"open", "merged"},
+	}
+	want := Config{
+		Handles:  []Handle{{Handle: "octocat"}, {Handle: "hubot", Name: "Hu Bot"}, {Handle: "monalisa"}},
+		Orgs:     []string{"octo", "github"},
+		Scopes:   []Scope{{Org: "hub", Repos: []string{"cli", "web"}}, {Org: "whole"}},
+		Statuses: []string{"merged", "open"},
+	}
+	if got := a.Merge(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadConfigStatuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		want        []string
+		errContains string
+	}{
+		{name: "valid", yaml: "statuses: [open, closed, merged]", want: []string{"open", "closed", "merged"}},
+		{name: "empty", yaml: "statuses: []", want: []string{"merged"}},
+		{name: "unset", yaml: "orgs: [octo]", want: []string{"merged"}},
+		{name: "invalid", yaml: "statuses: [merged, mergd, Open]", errContains: `unknown statuses in config: "mergd", "Open"; valid statuses are`},
+	}
+	for _, tt := range tests {
+		config, err := loadYAML(t, "handles: [octocat]\n"+tt.yaml)
+		if tt.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("%s: err = %v, want one containing %q", tt.name, err, tt.errContains)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if !reflect.DeepEqual(config.Statuses, tt.want) {
+			t.Errorf("%s: statuses = %q, want %q", tt.name, config.Statuses, tt.want)
+		}
+	}
+}
+
+func TestDateFieldQualifiesEachStatus(t *testing.T) {
+	srv, queries := searchServer(t, "")
+	client := testClient(srv.URL)
+	client.Window = DateRange{Start: "2024-01-01"}
+	if _, err := client.Fetch(context.Background(), Config{
+		Handles:    []Handle{{Handle: "octocat"}},
+		Statuses:   []string{"merged", "open", "closed"},
+		DateFields: map[string]string{"open": "updated", "closed": "closed"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"is:merged":   " merged:>=2024-01-01",
+		"is:open":     " updated:>=2024-01-01",
+		"is:unmerged": " closed:>=2024-01-01",
+	}
+	got := queries()
+	if len(got) != len(want) {
+		t.Fatalf("queries = %q, want one per status", got)
+	}
+	for _, q := range got {
+		matched := false
+		for status, qualifier := range want {
+			if strings.Contains(q, status) {
+				matched = true
+				if !strings.Contains(q, qualifier) {
+					t.Errorf("query %q lacks %q", q, qualifier)
+				}
+			}
+		}
+		if !matched {
+			t.Errorf("unexpected query %q", q)
+		}
+	}
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	t.Setenv("PULLPANDA_TEST_ORG", "octo")
+	t.Setenv("PULLPANDA_TEST_HANDLE", "octocat")
+	const yaml = `
+handles:
+  - handle: ${PULLPANDA_TEST_HANDLE}
+    name: Octo $PULLPANDA_TEST_ORG
+orgs: [$PULLPANDA_TEST_ORG, "${PULLPANDA_TEST_UNSET}"]
+repos: [hub/cli]
+`
+	config, err := loadYAML(t, yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Handle{{Handle: "octocat", Name: "Octo octo"}}; !reflect.DeepEqual(config.Handles, want) {
+		t.Errorf("handles = %+v, want %+v", config.Handles, want)
+	}
+	// The unset variable leaves an empty org, which is dropped
+	if want := []string{"octo"}; !reflect.DeepEqual(config.Orgs, want) {
+		t.Errorf("orgs = %q, want %q", config.Orgs, want)
+	}
+	if want := []string{"hub/cli"}; !reflect.DeepEqual(config.Repos, want) {
+		t.Errorf("repos = %q, want the literal %q", config.Repos, want)
+	}
+
+	_, err = ConfigLoader{Stdin: strings.NewReader(yaml), StrictEnv: true}.Load(StdinConfig)
+	if err == nil || !strings.Contains(err.Error(), "undefined environment variables: PULLPANDA_TEST_UNSET") {
+		t.Errorf("strict err = %v, want the unset variable named", err)
+	}
+}
+
+func TestForHandleMixesGlobalAndOwnScopes(t *testing.T) {
+	srv, queries := searchServer(t, "")
+	if _, err := testClient(srv.URL).Fetch(context.Background(), Config{
+		Handles: []Handle{
+			{Handle: "octocat"},
+			{Handle: "hubot", Repos: []string{"acme/api"}},
+			{Handle: "monalisa", Orgs: []string{"acme"}},
+		},
+		Orgs:     []string{"octo"},
+		Scopes:   []Scope{{Org: "github", Repos: []string{"docs"}}},
+		Statuses: []string{"merged"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	scopes := make(map[string][]string)
+	for _, q := range queries() {
+		var author string
+		var scoped []string
+		for _, term := range strings.Fields(q) {
+			switch {
+			case strings.HasPrefix(term, "author:"):
+				author = strings.TrimPrefix(term, "author:")
+			case strings.HasPrefix(term, "org:"), strings.HasPrefix(term, "repo:"):
+				scoped = append(scoped, term)
+			}
+		}
+		scopes[author] = append(scopes[author], strings.Join(scoped, " "))
+	}
+	for handle, want := range map[string][]string{
+		"octocat":  {"org:github repo:github/docs", "org:octo"},
+		"hubot":    {"repo:acme/api"},
+		"monalisa": {"org:acme"},
+	} {
+		got := scopes[handle]
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s searched %q, want %q", handle, got, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package pullpanda
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateRange is an inclusive window of YYYY-MM-DD dates. Either end may be
+// empty to leave that side open.
+type DateRange struct {
+	Start string
+	End   string
+	// Location is the time zone the dates are days in. GitHub matches plain
+	// dates in UTC, so for other zones the qualifiers use timestamps with the
+	// zone's offset instead. Nil means UTC.
+	Location *time.Location
+}
+
+// Qualifiers returns the search qualifiers restricting status to the window.
+// Merged PRs are matched on their merge date, everything else on creation.
+func (r DateRange) Qualifiers(status string) string {
+	return r.FieldQualifiers(defaultDateField(status))
+}
+
+func defaultDateField(status string) string {
+	if status == "merged" {
+		return "merged"
+	}
+	return "created"
+}
+
+// FieldQualifiers returns the search qualifiers restricting field, e.g.
+// "closed", to the window.
+func (r DateRange) FieldQualifiers(field string) string {
+	var q string
+	if r.Start != "" {
+		q += fmt.Sprintf(" %s:>=%s", field, r.bound(r.Start, false))
+	}
+	if r.End != "" {
+		q += fmt.Sprintf(" %s:<=%s", field, r.bound(r.End, true))
+	}
+	return q
+}
+
+// bound returns date as is for UTC, or otherwise as the timestamp of the
+// start of the day in Location, or of its last second when endOfDay is set.
+// The last second is found from the next midnight, since days changing to or
+// from daylight saving time are not 24 hours long.
+func (r DateRange) bound(date string, endOfDay bool) string {
+	if r.Location == nil || r.Location == time.UTC {
+		return date
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, r.Location)
+	if err != nil {
+		return date
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return day.Format("2006-01-02T15:04:05-07:00")
+}
+
+// startTime returns the beginning of the window's first day in Location, and
+// false when the window has no valid start.
+func (r DateRange) startTime() (time.Time, bool) {
+	location := r.Location
+	if location == nil {
+		location = time.UTC
+	}
+	day, err := time.ParseInLocation("2006-01-02", r.Start, location)
+	return day, err == nil
+}
+
+func (r DateRange) String() string {
+	start, end := r.Start, r.End
+	if start == "" {
+		start = "*"
+	}
+	if end == "" {
+		end = "*"
+	}
+	return start + ".." + end
+}
@@ -0,0 +1,74 @@
+package pullpanda
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFieldQualifiersInLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  string
+	}{
+		{
+			name:  "ordinary day",
+			start: "2024-01-10",
+			end:   "2024-01-10",
+			want:  " merged:>=2024-01-10T00:00:00-05:00 merged:<=2024-01-10T23:59:59-05:00",
+		},
+		{
+			// 23 hours long, clocks go forward at 2am
+			name:  "spring forward",
+			start: "2024-03-10",
+			end:   "2024-03-10",
+			want:  " merged:>=2024-03-10T00:00:00-05:00 merged:<=2024-03-10T23:59:59-04:00",
+		},
+		{
+			// 25 hours long, clocks go back at 2am
+			name:  "fall back",
+			start: "2024-11-03",
+			end:   "2024-11-03",
+			want:  " merged:>=2024-11-03T00:00:00-04:00 merged:<=2024-11-03T23:59:59-05:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := DateRange{Start: tt.start, End: tt.end, Location: newYork}
+			if got := window.FieldQualifiers("merged"); got != tt.want {
+				t.Errorf("FieldQualifiers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldQualifiersInUTC(t *testing.T) {
+	window := DateRange{Start: "2024-03-10", End: "2024-03-16"}
+	if got, want := window.FieldQualifiers("created"), " created:>=2024-03-10 created:<=2024-03-16"; got != want {
+		t.Errorf("FieldQualifiers = %q, want %q", got, want)
+	}
+}
+
+func TestQualifiersForOpenEndedWindows(t *testing.T) {
+	tests := []struct {
+		window DateRange
+		status string
+		want   string
+	}{
+		{DateRange{Start: "2024-01-01"}, "merged", " merged:>=2024-01-01"},
+		{DateRange{End: "2024-01-31"}, "merged", " merged:<=2024-01-31"},
+		{DateRange{Start: "2024-01-01", End: "2024-01-31"}, "merged", " merged:>=2024-01-01 merged:<=2024-01-31"},
+		{DateRange{End: "2024-01-31"}, "open", " created:<=2024-01-31"},
+		{DateRange{}, "open", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.window.Qualifiers(tt.status); got != tt.want {
+			t.Errorf("%v Qualifiers(%q) = %q, want %q", tt.window, tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+)
+
+// Values of Client.Forks.
+const (
+	ExcludeForks = "exclude"
+	OnlyForks    = "only"
+)
+
+// filterByFork keeps the PRs whose repository is or isn't a fork, as
+// Client.Forks asks, or all of them when it is unset. The search API has no
+// fork qualifier for PRs, so each repository is looked up once.
+func (c *Client) filterByFork(ctx context.Context, prs []PullRequest) ([]PullRequest, error) {
+	if c.Forks == "" {
+		return prs, nil
+	}
+	var kept []PullRequest
+	for _, pr := range prs {
+		if pr.Repository == "" {
+			return nil, fmt.Errorf("unrecognized pull request URL %q", pr.URL)
+		}
+		fork, err := c.isFork(ctx, pr.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("error checking whether %s is a fork: %w", pr.Repository, err)
+		}
+		if fork == (c.Forks == OnlyForks) {
+			kept = append(kept, pr)
+		}
+	}
+	return kept, nil
+}
+
+// isFork reports whether repo, e.g. "octo/api", is a fork, looked up once
+// per Client through the repos API.
+func (c *Client) isFork(ctx context.Context, repo string) (bool, error) {
+	s := c.state()
+	s.forksMu.Lock()
+	fork, ok := s.forks[repo]
+	s.forksMu.Unlock()
+	if ok {
+		return fork, nil
+	}
+	var body struct {
+		Fork bool `json:"fork"`
+	}
+	if err := c.fetchJSON(ctx, "/repos/"+repo, &body); err != nil {
+		return false, err
+	}
+	s.forksMu.Lock()
+	s.forks[repo] = body.Fork
+	s.forksMu.Unlock()
+	return body.Fork, nil
+}
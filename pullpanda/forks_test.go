@@ -0,0 +1,65 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFilterByFork(t *testing.T) {
+	var lookups int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		switch r.URL.Path {
+		case "/repos/octo/api":
+			fmt.Fprint(w, `{"fork":false}`)
+		case "/repos/me/api":
+			fmt.Fprint(w, `{"fork":true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	prs := []PullRequest{
+		{Repository: "octo/api", Number: 1},
+		{Repository: "me/api", Number: 2},
+		{Repository: "octo/api", Number: 3},
+	}
+	tests := []struct {
+		forks string
+		want  []int
+	}{
+		{"", []int{1, 2, 3}},
+		{ExcludeForks, []int{1, 3}},
+		{OnlyForks, []int{2}},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt32(&lookups, 0)
+		client := testClient(srv.URL)
+		client.Forks = tt.forks
+		kept, err := client.filterByFork(context.Background(), prs)
+		if err != nil {
+			t.Fatalf("Forks %q: %v", tt.forks, err)
+		}
+		var got []int
+		for _, pr := range kept {
+			got = append(got, pr.Number)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Forks %q kept %v, want %v", tt.forks, got, tt.want)
+		}
+		if tt.forks != "" && atomic.LoadInt32(&lookups) != 2 {
+			t.Errorf("Forks %q looked up %d repos, want each of the 2 once", tt.forks, lookups)
+		}
+	}
+
+	client := testClient(srv.URL)
+	client.Forks = OnlyForks
+	if _, err := client.filterByFork(context.Background(), []PullRequest{{Repository: "octo/gone"}}); err == nil {
+		t.Error("a repository that can't be looked up didn't fail the filter")
+	}
+}
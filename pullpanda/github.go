@@ -0,0 +1,361 @@
+package pullpanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newRequest creates a GET request for a path of the GitHub API, carrying
+// the auth and accept headers every call needs.
+func (c *Client) newRequest(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(c.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.authToken(ctx, resourceOf(path))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", c.acceptHeader("application/vnd.github.v3+json"))
+	req.Header.Set("User-Agent", userAgent(c.UserAgent))
+	return req, nil
+}
+
+// acceptHeader returns the Accept header asking for media, followed by the
+// extra media types of Client.Accept.
+func (c *Client) acceptHeader(media string) string {
+	return strings.Join(append([]string{media}, c.Accept...), ", ")
+}
+
+// userAgent returns ua, or DefaultUserAgent when it's empty.
+func userAgent(ua string) string {
+	if ua == "" {
+		return DefaultUserAgent()
+	}
+	return ua
+}
+
+// authToken returns the token to send for a request drawing from the
+// resource rate limit: from TokenSource when it's set, the next of Tokens
+// when they are, and Token otherwise.
+func (c *Client) authToken(ctx context.Context, resource string) (string, error) {
+	if c.TokenSource != nil {
+		return c.TokenSource(ctx)
+	}
+	if len(c.Tokens) > 0 {
+		return c.pickToken(resource), nil
+	}
+	return c.Token, nil
+}
+
+// fetchJSON GETs a GitHub API path and decodes the JSON response into v.
+func (c *Client) fetchJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := c.newRequest(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.recordTokenExpiry(resp.Header)
+	c.recordTokenBudget(req, resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, "")
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// APIError is a non-200 response from the GitHub API, carrying the message
+// GitHub sent back and the search query that triggered it, if any.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Details    []string
+	Query      string
+}
+
+// statusHints suggests a fix for the statuses users most often run into.
+var statusHints = map[int]string{
+	http.StatusUnauthorized:        "check that the token is valid and has not expired",
+	http.StatusForbidden:           "the token may lack the required scopes, or the rate limit has been exceeded",
+	http.StatusNotFound:            "check that the configured orgs and repos exist and the token can access them",
+	http.StatusUnprocessableEntity: "the search query is invalid; check handles, orgs, repos and dates in the config",
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("GitHub API returned %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if len(e.Details) > 0 {
+		msg += " (" + strings.Join(e.Details, "; ") + ")"
+	}
+	if e.Query != "" {
+		msg += fmt.Sprintf(" for query %q", e.Query)
+	}
+	if hint, ok := statusHints[e.StatusCode]; ok {
+		msg += "; hint: " + hint
+	}
+	return msg
+}
+
+// newAPIError decodes the error body GitHub returns alongside non-200
+// responses. Bodies that aren't JSON still produce an error with the status.
+func newAPIError(resp *http.Response, query string) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Query: query}
+
+	var body struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+			Code    string `json:"code"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		apiErr.Message = body.Message
+		for _, e := range body.Errors {
+			switch {
+			case e.Message != "":
+				apiErr.Details = append(apiErr.Details, e.Message)
+			case e.Field != "":
+				apiErr.Details = append(apiErr.Details, fmt.Sprintf("%s is %s", e.Field, e.Code))
+			}
+		}
+	}
+	return apiErr
+}
+
+// searchResult is a page of the issue search API.
+type searchResult struct {
+	TotalCount int           `json:"total_count"`
+	Items      []PullRequest `json:"items"`
+	// IncompleteResults is set when the search timed out on GitHub's side
+	// and the page may be missing matches.
+	IncompleteResults bool `json:"incomplete_results"`
+}
+
+// incompleteRetries is how often a search reporting incomplete results is
+// repeated, waiting incompleteRetryDelay times the attempt in between.
+const incompleteRetries = 2
+
+var incompleteRetryDelay = time.Second
+
+// makeRequest fetches a search page, repeating it while GitHub reports
+// incomplete results. If they stay incomplete, the last page is returned with
+// IncompleteResults set.
+func (c *Client) makeRequest(ctx context.Context, searchPath string) (searchResult, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := c.searchOnce(ctx, searchPath)
+		if err != nil || !result.IncompleteResults || attempt > incompleteRetries {
+			return result, err
+		}
+		c.logf("Search returned incomplete results, retrying: %s\n", searchQuery(searchPath))
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(time.Duration(attempt) * incompleteRetryDelay):
+		}
+	}
+}
+
+// searchOnce fetches a search page, or serves it from the page cache.
+func (c *Client) searchOnce(ctx context.Context, searchPath string) (searchResult, error) {
+	var result searchResult
+
+	if data, ok := c.cachedPage(searchPath); ok {
+		if err := json.Unmarshal(data, &result); err == nil {
+			c.logf("Using cached page for %s\n", searchPath)
+			return result, nil
+		}
+		result = searchResult{}
+	}
+
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, searchPath)
+		if err != nil {
+			return result, fmt.Errorf("error creating request: %w", err)
+		}
+		if strings.HasPrefix(searchPath, commitsSearch) {
+			// The commit search used to be a preview only served with this
+			// media type, which GitHub Enterprise Server may still require
+			req.Header.Set("Accept", c.acceptHeader("application/vnd.github.cloak-preview+json"))
+		}
+
+		resp, err = c.HTTPClient.Do(req)
+		if err != nil {
+			return result, fmt.Errorf("error making request: %w", err)
+		}
+		c.recordRateLimit(resp.Header)
+		c.recordTokenExpiry(resp.Header)
+		c.recordTokenBudget(req, resp.Header)
+		if !isRateLimited(resp) || attempt >= len(c.Tokens) || !c.hasSpareToken(requestToken(req), "search") {
+			break
+		}
+		resp.Body.Close()
+		c.logf("Rate limit of a token used up, retrying with the next one: %s\n", searchQuery(searchPath))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return result, newAPIError(resp, searchQuery(searchPath))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return result, fmt.Errorf("error reading response: %w", err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("error decoding response: %w", err)
+	}
+	if !result.IncompleteResults {
+		c.storePage(searchPath, data)
+	}
+
+	return result, nil
+}
+
+// RateLimit is the lowest remaining rate-limit budget seen by a Client.
+type RateLimit struct {
+	Remaining int
+	Reset     time.Time
+}
+
+// recordRateLimit keeps the rate-limit headers of resp if its remaining
+// budget is the lowest seen so far.
+func (c *Client) recordRateLimit(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, _ := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+
+	s := c.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rateLimit == nil || remaining < s.rateLimit.Remaining {
+		s.rateLimit = &RateLimit{Remaining: remaining, Reset: time.Unix(reset, 0)}
+	}
+}
+
+// tokenExpiryLayouts are the formats GitHub has used for the
+// GitHub-Authentication-Token-Expiration header.
+var tokenExpiryLayouts = []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700", time.RFC3339}
+
+// recordTokenExpiry passes the token expiration header, the first time one
+// is seen, to OnTokenExpiry.
+func (c *Client) recordTokenExpiry(h http.Header) {
+	value := h.Get("GitHub-Authentication-Token-Expiration")
+	if c.OnTokenExpiry == nil || value == "" {
+		return
+	}
+	var expires time.Time
+	var err error
+	for _, layout := range tokenExpiryLayouts {
+		if expires, err = time.Parse(layout, value); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		c.logf("Ignoring unparseable token expiration %q\n", value)
+		return
+	}
+
+	s := c.state()
+	s.mu.Lock()
+	seen := s.tokenExpiry
+	s.tokenExpiry = true
+	s.mu.Unlock()
+	if !seen {
+		c.OnTokenExpiry(expires)
+	}
+}
+
+// RateLimit returns the lowest remaining rate limit seen so far, and false
+// when no response carried rate-limit headers yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	s := c.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rateLimit == nil {
+		return RateLimit{}, false
+	}
+	return *s.rateLimit, true
+}
+
+// searchPageSize is the largest page the search API serves, and the page
+// size used unless PageSize is set.
+const searchPageSize = 100
+
+// pageSize returns the per_page of search listings.
+func (c *Client) pageSize() int {
+	if c.PageSize > 0 && c.PageSize < searchPageSize {
+		return c.PageSize
+	}
+	return searchPageSize
+}
+
+// searchResultCap is the number of results the search API returns at most
+// for one query, however many pages are requested.
+const searchResultCap = 1000
+
+// searchPages follows the pages of a search until maxItems items were
+// collected, or all of them when maxItems is 0. TotalCount is the full number
+// of matches even when fewer items are returned. Each page is requested on
+// its own, so a retried request only repeats the page that failed. Items
+// seen on an earlier page are skipped: results shift between pages when PRs
+// change mid-search, and would otherwise be counted twice.
+func (c *Client) searchPages(ctx context.Context, searchPath string, maxItems int) (searchResult, error) {
+	var all searchResult
+	seen := make(map[string]bool)
+	pageSize := c.pageSize()
+	for page := 1; ; page++ {
+		result, err := c.makeRequest(ctx, fmt.Sprintf("%s&per_page=%d&page=%d", searchPath, pageSize, page))
+		if err != nil {
+			return all, err
+		}
+		all.TotalCount = result.TotalCount
+		for _, pr := range result.Items {
+			if key := pr.key(); key == "" || !seen[key] {
+				seen[key] = true
+				all.Items = append(all.Items, pr)
+			} else {
+				c.logf("Skipping %s, already returned on an earlier page\n", key)
+			}
+		}
+		all.IncompleteResults = all.IncompleteResults || result.IncompleteResults
+
+		if maxItems > 0 && len(all.Items) >= maxItems {
+			all.Items = all.Items[:maxItems]
+			break
+		}
+		// The cap counts every result returned, skipped repeats included
+		if len(result.Items) < pageSize || len(all.Items) >= all.TotalCount || page*pageSize >= searchResultCap {
+			break
+		}
+	}
+	return all, nil
+}
+
+// searchQuery extracts the unescaped q parameter from a search path.
+func searchQuery(searchPath string) string {
+	u, err := url.Parse(searchPath)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("q")
+}
@@ -0,0 +1,403 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pagedSearchServer answers every issue search with total PRs titled
+// "fix N", served page by page up to the search API's 1000 result cap, and
+// counts the pages it served.
+func pagedSearchServer(t *testing.T, total int) (*httptest.Server, *int32) {
+	t.Helper()
+	var pages int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pages, 1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page = max(page, 1)
+		var items []string
+		for i := (page - 1) * perPage; i < min(page*perPage, total, searchResultCap); i++ {
+			items = append(items, fmt.Sprintf(`{"url":"https://api.github.com/repos/octo/api/issues/%d","number":%d,"title":"fix %d","repository_url":"https://api.github.com/repos/octo/api"}`, i, i, i))
+		}
+		fmt.Fprintf(w, `{"total_count":%d,"items":[%s]}`, total, strings.Join(items, ","))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &pages
+}
+
+// TestSearchPagesStopsAtResultCap pages through a search whose results repeat
+// across pages, answering with GitHub's 422 past the 1000th result.
+func TestSearchPagesStopsAtResultCap(t *testing.T) {
+	pages := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if page*perPage > searchResultCap {
+			http.Error(w, `{"message":"Only the first 1000 search results are available"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		pages++
+		// Every page repeats the last item of the one before
+		var items []string
+		for i := 0; i < perPage; i++ {
+			n := (page-1)*(perPage-1) + i
+			items = append(items, fmt.Sprintf(`{"url":"https://api.github.com/repos/octo/api/issues/%d","number":%d}`, n, n))
+		}
+		fmt.Fprintf(w, `{"total_count":5000,"items":[%s]}`, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	result, err := client.searchPages(context.Background(), "/search/issues?q=author%3Aoctocat", 0)
+	if err != nil {
+		t.Fatalf("searchPages failed past the result cap: %v", err)
+	}
+	if pages != searchResultCap/searchPageSize {
+		t.Errorf("fetched %d pages, want %d", pages, searchResultCap/searchPageSize)
+	}
+	if want := searchResultCap - pages + 1; len(result.Items) != want {
+		t.Errorf("got %d unique items, want %d", len(result.Items), want)
+	}
+}
+
+// TestAPIErrorSurfacesMessage feeds the error bodies GitHub sends back and
+// checks the message, details, query and hint all reach the error.
+func TestAPIErrorSurfacesMessage(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   []string
+	}{
+		{
+			status: http.StatusUnauthorized,
+			body:   `{"message":"Bad credentials"}`,
+			want:   []string{"returned 401: Bad credentials", statusHints[http.StatusUnauthorized]},
+		},
+		{
+			status: http.StatusForbidden,
+			body:   `{"message":"Resource not accessible by integration"}`,
+			want:   []string{"returned 403: Resource not accessible by integration", statusHints[http.StatusForbidden]},
+		},
+		{
+			status: http.StatusNotFound,
+			body:   `{"message":"Not Found"}`,
+			want:   []string{"returned 404: Not Found", statusHints[http.StatusNotFound]},
+		},
+		{
+			status: http.StatusUnprocessableEntity,
+			body:   `{"message":"Validation Failed","errors":[{"message":"The listed users cannot be searched"},{"field":"q","code":"missing"}]}`,
+			want: []string{
+				"returned 422: Validation Failed (The listed users cannot be searched; q is missing)",
+				statusHints[http.StatusUnprocessableEntity],
+			},
+		},
+		{
+			status: http.StatusBadGateway,
+			body:   `<html>Bad Gateway</html>`,
+			want:   []string{"returned 502 for query"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			_, err := testClient(srv.URL).searchOnce(context.Background(), "/search/issues?q=author%3Aoctocat")
+			if err == nil {
+				t.Fatal("searchOnce succeeded, want an error")
+			}
+			want := append(tt.want, `for query "author:octocat"`)
+			for _, w := range want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+// TestCountOnlyFetchesOnePagePerQuery counts the searches of a CountOnly
+// fetch over several statuses and scopes, checking each query is sent once,
+// for a single one-item page, and counted as its total_count.
+func TestCountOnlyFetchesOnePagePerQuery(t *testing.T) {
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Query().Get("q")]++
+		if got := r.URL.Query().Get("per_page"); got != "1" {
+			t.Errorf("per_page = %q, want 1", got)
+		}
+		fmt.Fprint(w, `{"total_count":250,"items":[{"url":"https://api.github.com/repos/octo/api/issues/1","number":1}]}`)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.CountOnly = true
+	config := Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/api", "octo/web"},
+		Statuses: []string{"merged", "open"},
+	}
+	result, err := client.Fetch(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 4 {
+		t.Errorf("sent %d distinct queries, want 4: %v", len(requests), requests)
+	}
+	for q, n := range requests {
+		if n != 1 {
+			t.Errorf("query %q sent %d times, want once", q, n)
+		}
+	}
+	summary := result.Summaries[0]
+	for _, status := range config.Statuses {
+		if got := summary.Counts[status]; got != 500 {
+			t.Errorf("%s count = %d, want the totals of both repos, 500", status, got)
+		}
+	}
+	if len(summary.PRs) != 0 {
+		t.Errorf("collected %d PRs, want none", len(summary.PRs))
+	}
+}
+
+// TestCountsFromCappedSearchAreMarked filters the PRs of a search matching
+// more than the 1000 it lists, checking the summary is marked as capped.
+func TestCountsFromCappedSearchAreMarked(t *testing.T) {
+	for _, tt := range []struct {
+		total int
+		want  bool
+	}{{total: 1500, want: true}, {total: 40, want: false}} {
+		srv, _ := pagedSearchServer(t, tt.total)
+		client := testClient(srv.URL)
+		client.TitleMatch = regexp.MustCompile(`^fix`)
+		client.MatchAffectsCounts = true
+		result, err := client.Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary := result.Summaries[0]
+		if summary.Capped != tt.want {
+			t.Errorf("total %d: Capped = %v, want %v", tt.total, summary.Capped, tt.want)
+		}
+		if want := min(tt.total, searchResultCap); summary.Counts["merged"] != want {
+			t.Errorf("total %d: counted %d, want %d", tt.total, summary.Counts["merged"], want)
+		}
+	}
+}
+
+// TestLimitCapsPRsButNotCounts fetches with Limit set below the number of
+// matching PRs, checking only Limit PRs are listed while the count is the
+// search's total_count, and that paging stops once the limit is reached.
+func TestLimitCapsPRsButNotCounts(t *testing.T) {
+	srv, pages := pagedSearchServer(t, 250)
+	client := testClient(srv.URL)
+	client.Limit = 30
+	client.PageSize = 20
+
+	result, err := client.Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := result.Summaries[0]
+	if len(summary.PRs) != 30 {
+		t.Errorf("listed %d PRs, want 30", len(summary.PRs))
+	}
+	if summary.Counts["merged"] != 250 {
+		t.Errorf("merged count = %d, want the total_count 250", summary.Counts["merged"])
+	}
+	if !summary.Truncated {
+		t.Error("summary isn't marked as truncated")
+	}
+	if got := atomic.LoadInt32(pages); got != 2 {
+		t.Errorf("fetched %d pages, want the 2 holding the first 30 PRs", got)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+		fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+	}))
+	defer srv.Close()
+	defer func(version string) { Version = version }(Version)
+	Version = "1.2.3"
+
+	tests := []struct {
+		name  string
+		setUA func(c *Client)
+		want  string
+	}{
+		{"default", func(c *Client) {}, "pullpanda/1.2.3"},
+		{"cleared", func(c *Client) { c.UserAgent = "" }, "pullpanda/1.2.3"},
+		{"custom", func(c *Client) { c.UserAgent = "octo-reports/0.1" }, "octo-reports/0.1"},
+	}
+	for _, tt := range tests {
+		got = nil
+		client := testClient(srv.URL)
+		tt.setUA(client)
+		if _, err := client.searchOnce(context.Background(), "/search/issues?q=author%3Aoctocat"); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: User-Agent = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestIncompleteResultsAreRetried answers searches with incomplete results a
+// number of times before completing them, and checks the search is repeated
+// and a warning only raised when retrying didn't help.
+func TestIncompleteResultsAreRetried(t *testing.T) {
+	defer func(delay time.Duration) { incompleteRetryDelay = delay }(incompleteRetryDelay)
+	incompleteRetryDelay = time.Millisecond
+	tests := []struct {
+		incomplete   int32
+		wantRequests int32
+		wantWarning  bool
+	}{
+		{0, 1, false},
+		{2, 3, false},
+		{5, 3, true},
+	}
+	for _, tt := range tests {
+		var requests int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&requests, 1)
+			fmt.Fprintf(w, `{"total_count":1,"incomplete_results":%v,"items":[{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"repository_url":"https://api.github.com/repos/o/r"}]}`, n <= tt.incomplete)
+		}))
+		result, err := testClient(srv.URL).Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requests != tt.wantRequests {
+			t.Errorf("incomplete %d times: sent %d requests, want %d", tt.incomplete, requests, tt.wantRequests)
+		}
+		warned := len(result.Warnings) == 1 && strings.Contains(result.Warnings[0], "incomplete search results for octocat")
+		if warned != tt.wantWarning || result.Summaries[0].Incomplete != tt.wantWarning {
+			t.Errorf("incomplete %d times: warnings %q, Incomplete %v, want a warning %v", tt.incomplete, result.Warnings, result.Summaries[0].Incomplete, tt.wantWarning)
+		}
+	}
+}
+
+// TestCacheReusesFetchedPages caches the first page of a search with Limit,
+// then lists the whole search and checks only the second page is fetched.
+func TestCacheReusesFetchedPages(t *testing.T) {
+	srv, pages := pagedSearchServer(t, 150)
+	cacheDir := t.TempDir()
+	fetch := func(limit int) Summary {
+		t.Helper()
+		client := testClient(srv.URL)
+		client.CacheDir = cacheDir
+		client.Limit = limit
+		result, err := client.Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Summaries[0]
+	}
+
+	if got := fetch(100); len(got.PRs) != 100 || atomic.LoadInt32(pages) != 1 {
+		t.Fatalf("listed %d PRs from %d pages, want 100 from the first", len(got.PRs), *pages)
+	}
+	got := fetch(0)
+	if len(got.PRs) != 150 || got.Counts["merged"] != 150 {
+		t.Errorf("listed %d PRs counted %d, want 150", len(got.PRs), got.Counts["merged"])
+	}
+	if n := atomic.LoadInt32(pages); n != 2 {
+		t.Errorf("served %d pages in all, want the second page fetched live and the first from the cache", n)
+	}
+}
+
+func TestPageSizeSetsPerPage(t *testing.T) {
+	tests := []struct {
+		pageSize  int
+		wantPages []string
+	}{
+		{30, []string{"30", "30", "30"}},
+		{0, []string{"100"}},
+		{250, []string{"100"}},
+	}
+	for _, tt := range tests {
+		var perPage []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			perPage = append(perPage, r.URL.Query().Get("per_page"))
+			size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			var items []string
+			for i := (page - 1) * size; i < min(page*size, 75); i++ {
+				items = append(items, fmt.Sprintf(`{"url":"https://api.github.com/repos/octo/api/issues/%d","number":%d,"repository_url":"https://api.github.com/repos/octo/api"}`, i, i))
+			}
+			fmt.Fprintf(w, `{"total_count":75,"items":[%s]}`, strings.Join(items, ","))
+		}))
+		client := testClient(srv.URL)
+		client.PageSize = tt.pageSize
+		result, err := client.Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(perPage, tt.wantPages) {
+			t.Errorf("PageSize %d requested per_page %v, want %v", tt.pageSize, perPage, tt.wantPages)
+		}
+		if got := result.Summaries[0].Counts["merged"]; got != 75 {
+			t.Errorf("PageSize %d counted %d, want 75", tt.pageSize, got)
+		}
+	}
+}
+
+func TestAcceptSendsExtraMediaTypes(t *testing.T) {
+	tests := []struct {
+		accept []string
+		want   map[string]string
+	}{
+		{nil, map[string]string{
+			"/search/issues":  "application/vnd.github.v3+json",
+			"/search/commits": "application/vnd.github.cloak-preview+json",
+		}},
+		{[]string{"application/vnd.github.squirrel-girl-preview", "application/vnd.github.mercy-preview+json"}, map[string]string{
+			"/search/issues":  "application/vnd.github.v3+json, application/vnd.github.squirrel-girl-preview, application/vnd.github.mercy-preview+json",
+			"/search/commits": "application/vnd.github.cloak-preview+json, application/vnd.github.squirrel-girl-preview, application/vnd.github.mercy-preview+json",
+		}},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		got := make(map[string]string)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			if accept, seen := got[r.URL.Path]; seen && accept != r.Header.Get("Accept") {
+				t.Errorf("%s sent Accept %q and %q", r.URL.Path, accept, r.Header.Get("Accept"))
+			}
+			got[r.URL.Path] = r.Header.Get("Accept")
+			mu.Unlock()
+			fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+		}))
+		client := testClient(srv.URL)
+		client.Accept = tt.accept
+		client.IncludeCommits = true
+		_, err := client.Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}, {Handle: "hubot"}}, Statuses: []string{"merged", "open"}})
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Accept %q sent %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package pullpanda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// graphQLBatchSize is the number of search queries sent per GraphQL request,
+// kept well below the API's node and complexity limits.
+const graphQLBatchSize = 20
+
+const reviewCommentsStatus = "review-comments"
+
+// countQuery is one search whose total is added to a handle's counts. An
+// empty status counts issues, reviewCommentsStatus commented PRs.
+type countQuery struct {
+	handle int
+	status string
+	scope  searchScope
+	query  string
+}
+
+// countQueries lists every search the REST backend would make for config,
+// one per handle, status and scope.
+func (c *Client) countQueries(config Config) []countQuery {
+	var queries []countQuery
+	for i, handle := range config.Handles {
+		config := config.forHandle(handle)
+		scopes := searchScopes(config.Orgs, config.Repos, config.Scopes)
+		for _, status := range config.Statuses {
+			query := fmt.Sprintf("author:%s is:pr", handle.Handle) + statusQualifiers(status, config.Statuses) + c.Window.FieldQualifiers(config.DateField(status)) + c.searchFilters() + authorExclusions(config.ExcludeAuthors)
+			for _, scope := range scopes {
+				queries = append(queries, countQuery{i, status, scope, query})
+			}
+		}
+		if c.IncludeIssues {
+			query := fmt.Sprintf("author:%s is:issue", handle.Handle) + c.Window.Qualifiers("") + c.issueFilters() + authorExclusions(config.ExcludeAuthors)
+			for _, scope := range scopes {
+				queries = append(queries, countQuery{i, "", scope, query})
+			}
+		}
+		if c.IncludeReviewComments {
+			query := c.reviewCommentsQuery(handle.Handle, config)
+			for _, scope := range scopes {
+				queries = append(queries, countQuery{i, reviewCommentsStatus, scope, query})
+			}
+		}
+	}
+	return queries
+}
+
+// SearchURLs returns the search API URL of every query Fetch would start
+// with for config, one per handle, status and scope. Pagination parameters
+// and follow-up requests, such as CODEOWNERS lookups, aren't included. The
+// commit searches of IncludeCommits and the merged PR searches of
+// IncludeCoAuthored come last.
+func (c *Client) SearchURLs(config Config) []string {
+	config.Statuses = mergeLists(config.Statuses, nil)
+	var urls []string
+	for _, q := range c.countQueries(config) {
+		urls = append(urls, strings.TrimSuffix(c.BaseURL, "/")+issuesSearch+"?q="+url.QueryEscape(q.query+q.scope.qualifier))
+	}
+	if c.IncludeCommits {
+		for _, handle := range config.Handles {
+			config := config.forHandle(handle)
+			for _, scope := range searchScopes(config.Orgs, config.Repos, config.Scopes) {
+				urls = append(urls, strings.TrimSuffix(c.BaseURL, "/")+commitsSearch+"?q="+url.QueryEscape(c.commitsQuery(handle.Handle)+scope.qualifier))
+			}
+		}
+	}
+	if c.IncludeCoAuthored {
+		for _, handle := range config.Handles {
+			config := config.forHandle(handle)
+			for _, scope := range searchScopes(config.Orgs, config.Repos, config.Scopes) {
+				urls = append(urls, strings.TrimSuffix(c.BaseURL, "/")+issuesSearch+"?q="+url.QueryEscape(c.coAuthoredQuery(handle.Handle, config)+scope.qualifier))
+			}
+		}
+	}
+	return urls
+}
+
+// fetchGraphQL counts PRs for every handle through the GraphQL API, batching
+// the searches as aliases of a few documents. It only fills in counts, like
+// CountOnly, and fails as a whole on any error.
+func (c *Client) fetchGraphQL(ctx context.Context, config Config) (RunResult, error) {
+	summaries := make([]Summary, len(config.Handles))
+	for i, handle := range config.Handles {
+		summaries[i] = Summary{Handle: handle.Handle, Name: handle.Name, Counts: make(map[string]int)}
+	}
+
+	queries := c.countQueries(config)
+	for start := 0; start < len(queries); start += graphQLBatchSize {
+		batch := queries[start:min(start+graphQLBatchSize, len(queries))]
+		counts, err := c.graphQLCounts(ctx, batch)
+		if err != nil {
+			return RunResult{}, err
+		}
+		for i, q := range batch {
+			summary := &summaries[q.handle]
+			switch q.status {
+			case "":
+				summary.Issues += counts[i]
+				summary.addPartial("issues", q.scope, q.query, counts[i])
+			case reviewCommentsStatus:
+				summary.ReviewComments += counts[i]
+				summary.addPartial("review comments", q.scope, q.query, counts[i])
+			default:
+				summary.Counts[q.status] += counts[i]
+				summary.addPartial(q.status, q.scope, q.query, counts[i])
+			}
+		}
+	}
+	return RunResult{Summaries: summaries, Failures: make(map[string]error)}, nil
+}
+
+// graphQLCounts sends one document with an aliased search per query and
+// returns their issueCount totals in order.
+func (c *Client) graphQLCounts(ctx context.Context, queries []countQuery) ([]int, error) {
+	var params, fields []string
+	variables := make(map[string]string)
+	for i, q := range queries {
+		params = append(params, fmt.Sprintf("$q%d: String!", i))
+		fields = append(fields, fmt.Sprintf("q%d: search(query: $q%d, type: ISSUE, first: 1) { issueCount }", i, i))
+		variables[fmt.Sprintf("q%d", i)] = q.query + q.scope.qualifier
+		c.logf("Adding GraphQL search q%d: %s\n", i, q.query+q.scope.qualifier)
+	}
+	document := fmt.Sprintf("query(%s) {\n  %s\n}", strings.Join(params, ", "), strings.Join(fields, "\n  "))
+
+	var response struct {
+		Data   map[string]*struct{ IssueCount int } `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.postGraphQL(ctx, document, variables, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Errors) > 0 {
+		var messages []string
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return nil, fmt.Errorf("GraphQL query failed: %s", strings.Join(messages, "; "))
+	}
+
+	counts := make([]int, len(queries))
+	for i := range queries {
+		result := response.Data[fmt.Sprintf("q%d", i)]
+		if result == nil {
+			return nil, fmt.Errorf("GraphQL response is missing q%d", i)
+		}
+		counts[i] = result.IssueCount
+	}
+	return counts, nil
+}
+
+// graphQLURL returns the GraphQL endpoint matching BaseURL. GitHub
+// Enterprise Server serves REST under /api/v3 and GraphQL under /api/graphql.
+func (c *Client) graphQLURL() string {
+	base := strings.TrimSuffix(c.BaseURL, "/")
+	if strings.HasSuffix(base, "/api/v3") {
+		return strings.TrimSuffix(base, "/v3") + "/graphql"
+	}
+	return base + "/graphql"
+}
+
+// postGraphQL POSTs a document to the /graphql endpoint and decodes the
+// response into v.
+func (c *Client) postGraphQL(ctx context.Context, document string, variables map[string]string, v interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": document, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.graphQLURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	token, err := c.authToken(ctx, "graphql")
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("User-Agent", userAgent(c.UserAgent))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.recordRateLimit(resp.Header)
+	c.recordTokenExpiry(resp.Header)
+	c.recordTokenBudget(req, resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, "")
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
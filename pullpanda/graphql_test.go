@@ -0,0 +1,128 @@
+package pullpanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// graphQLServer answers GraphQL documents with an issueCount per aliased
+// search, 2 for merged queries and 1 for the others, and REST searches with
+// a total of 7. It counts the requests of each kind.
+func graphQLServer(t *testing.T, graphQLStatus int) (srv *httptest.Server, documents, searches *int32) {
+	t.Helper()
+	documents, searches = new(int32), new(int32)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search/issues" {
+			atomic.AddInt32(searches, 1)
+			fmt.Fprint(w, `{"total_count":7,"items":[]}`)
+			return
+		}
+		if r.URL.Path != "/graphql" || r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(documents, 1)
+		if graphQLStatus != http.StatusOK {
+			http.Error(w, `{"message":"Bad Gateway"}`, graphQLStatus)
+			return
+		}
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("undecodable GraphQL request: %v", err)
+		}
+		data := make(map[string]interface{})
+		for alias, query := range body.Variables {
+			if !strings.Contains(body.Query, alias+": search(query: $"+alias) {
+				t.Errorf("document %q has no search for %s", body.Query, alias)
+			}
+			count := 1
+			if strings.Contains(query, "is:merged") {
+				count = 2
+			}
+			data[alias] = map[string]int{"issueCount": count}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, documents, searches
+}
+
+func TestUseGraphQLBatchesCountSearches(t *testing.T) {
+	srv, documents, searches := graphQLServer(t, http.StatusOK)
+	client := testClient(srv.URL)
+	client.UseGraphQL = true
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}, {Handle: "hubot"}},
+		Repos:    []string{"octo/api", "octo/web"},
+		Statuses: []string{"merged", "open"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *documents != 1 || *searches != 0 {
+		t.Errorf("sent %d GraphQL documents and %d REST searches, want the 8 searches in 1 document", *documents, *searches)
+	}
+	for _, summary := range result.Summaries {
+		if summary.Counts["merged"] != 4 || summary.Counts["open"] != 2 {
+			t.Errorf("%s counts = %v, want merged 4 and open 2 over the two repos", summary.Handle, summary.Counts)
+		}
+	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("unexpected warnings %q", result.Warnings)
+	}
+}
+
+func TestUseGraphQLFallsBackToREST(t *testing.T) {
+	srv, documents, searches := graphQLServer(t, http.StatusBadGateway)
+	client := testClient(srv.URL)
+	client.UseGraphQL = true
+	client.CountOnly = true
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *documents != 1 || *searches != 1 {
+		t.Errorf("sent %d GraphQL documents and %d REST searches, want 1 of each", *documents, *searches)
+	}
+	if got := result.Summaries[0].Counts["merged"]; got != 7 {
+		t.Errorf("merged = %d, want the REST total 7", got)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "falling back to the REST search API") {
+		t.Errorf("warnings = %q, want the fallback noted", result.Warnings)
+	}
+}
+
+func TestSearchURLs(t *testing.T) {
+	client := testClient("https://ghe.example.com/api/v3/")
+	client.Window = DateRange{Start: "2024-01-01", End: "2024-01-31"}
+	client.IncludeCommits = true
+	got := client.SearchURLs(Config{
+		Handles:  []Handle{{Handle: "octocat"}, {Handle: "hubot", Repos: []string{"hub/cli"}}},
+		Orgs:     []string{"octo"},
+		Statuses: []string{"merged", "open", "merged"},
+	})
+	want := []string{
+		"https://ghe.example.com/api/v3/search/issues?q=author%3Aoctocat+is%3Apr+is%3Amerged+merged%3A%3E%3D2024-01-01+merged%3A%3C%3D2024-01-31+org%3Aocto",
+		"https://ghe.example.com/api/v3/search/issues?q=author%3Aoctocat+is%3Apr+is%3Aopen+created%3A%3E%3D2024-01-01+created%3A%3C%3D2024-01-31+org%3Aocto",
+		"https://ghe.example.com/api/v3/search/issues?q=author%3Ahubot+is%3Apr+is%3Amerged+merged%3A%3E%3D2024-01-01+merged%3A%3C%3D2024-01-31+repo%3Ahub%2Fcli",
+		"https://ghe.example.com/api/v3/search/issues?q=author%3Ahubot+is%3Apr+is%3Aopen+created%3A%3E%3D2024-01-01+created%3A%3C%3D2024-01-31+repo%3Ahub%2Fcli",
+		"https://ghe.example.com/api/v3/search/commits?q=author%3Aoctocat+author-date%3A%3E%3D2024-01-01+author-date%3A%3C%3D2024-01-31+org%3Aocto",
+		"https://ghe.example.com/api/v3/search/commits?q=author%3Ahubot+author-date%3A%3E%3D2024-01-01+author-date%3A%3C%3D2024-01-31+repo%3Ahub%2Fcli",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchURLs =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,41 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// filterByPaths keeps the PRs changing at least one file matched by
+// PathFilter, or all of them when it is unset. It needs the changed files of
+// every PR, one request per 100 files, cached like those of CODEOWNERS.
+func (c *Client) filterByPaths(ctx context.Context, prs []PullRequest) ([]PullRequest, error) {
+	if len(c.PathFilter) == 0 {
+		return prs, nil
+	}
+	patterns := make([]*regexp.Regexp, len(c.PathFilter))
+	for i, pattern := range c.PathFilter {
+		patterns[i] = codeownersPattern(pattern)
+	}
+
+	var matched []PullRequest
+	for _, pr := range prs {
+		if pr.Repository == "" || pr.Number == 0 {
+			return nil, fmt.Errorf("unrecognized pull request URL %q", pr.URL)
+		}
+		files, err := c.prFiles(ctx, pr.Repository, pr.Number)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the files of %s#%d: %w", pr.Repository, pr.Number, err)
+		}
+	files:
+		for _, file := range files {
+			for _, pattern := range patterns {
+				if pattern.MatchString(file) {
+					matched = append(matched, pr)
+					break files
+				}
+			}
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,76 @@
+package pullpanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestFilterByPaths(t *testing.T) {
+	// #3 only touches services/api on its second page of files
+	var vendored []string
+	for i := 0; i < 100; i++ {
+		vendored = append(vendored, fmt.Sprintf("vendor/lib/%d.go", i))
+	}
+	files := map[string][][]string{
+		"/repos/octo/api/pulls/1/files": {{"services/api/main.go", "README.md"}},
+		"/repos/octo/api/pulls/2/files": {{"docs/services/api.md"}},
+		"/repos/octo/api/pulls/3/files": {vendored, {"services/api/go.mod"}},
+		"/repos/octo/web/pulls/4/files": {{"ui/app.ts"}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages, ok := files[r.URL.Path]
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if !ok || page < 1 || page > len(pages) {
+			http.NotFound(w, r)
+			return
+		}
+		var result []map[string]string
+		for _, name := range pages[page-1] {
+			result = append(result, map[string]string{"filename": name})
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer srv.Close()
+
+	prs := []PullRequest{
+		{Repository: "octo/api", Number: 1},
+		{Repository: "octo/api", Number: 2},
+		{Repository: "octo/api", Number: 3},
+		{Repository: "octo/web", Number: 4},
+	}
+	tests := []struct {
+		filter []string
+		want   []int
+	}{
+		{nil, []int{1, 2, 3, 4}},
+		{[]string{"services/api/"}, []int{1, 3}},
+		{[]string{"*.md"}, []int{1, 2}},
+		{[]string{"ui/", "go.mod"}, []int{3, 4}},
+	}
+	for _, tt := range tests {
+		client := testClient(srv.URL)
+		client.PathFilter = tt.filter
+		kept, err := client.filterByPaths(context.Background(), prs)
+		if err != nil {
+			t.Fatalf("PathFilter %q: %v", tt.filter, err)
+		}
+		var got []int
+		for _, pr := range kept {
+			got = append(got, pr.Number)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("PathFilter %q kept %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	client := testClient(srv.URL)
+	client.PathFilter = []string{"services/"}
+	if _, err := client.filterByPaths(context.Background(), []PullRequest{{URL: "https://example.com/x"}}); err == nil {
+		t.Error("a PR without a repository didn't fail the filter")
+	}
+}
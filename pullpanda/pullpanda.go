@@ -0,0 +1,891 @@
+// Package pullpanda fetches pull request statistics for GitHub handles
+// through the search API. The pullpanda command is a thin wrapper around it;
+// other programs can use a Client directly:
+//
+//	client := pullpanda.NewClient(os.Getenv("GITHUB_TOKEN"))
+//	client.Window = pullpanda.DateRange{Start: "2024-01-01"}
+//	result, err := client.Fetch(ctx, config)
+package pullpanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the GitHub API used unless Client.BaseURL is changed.
+const DefaultBaseURL = "https://api.github.com"
+
+// Version is sent in the default User-Agent. Release builds set it with
+// -ldflags "-X guidewire.com/pullpanda/pullpanda.Version=1.2.3".
+var Version = "dev"
+
+// DefaultUserAgent is the User-Agent sent unless Client.UserAgent is changed.
+func DefaultUserAgent() string {
+	return "pullpanda/" + Version
+}
+
+// Client fetches PRs from the GitHub API. Create it with NewClient, then
+// adjust the exported fields before the first Fetch. One Client can Fetch
+// several configs in turn, reusing the connections of HTTPClient, its caches
+// and its rate-limit state.
+type Client struct {
+	Token string
+	// Tokens, when set, are used in turn instead of Token, to spread the
+	// requests over their rate limits. A token whose limit is used up is
+	// skipped until it resets, and a search refused for it is repeated with
+	// the next one.
+	Tokens []string
+	// TokenSource, when set, is asked for the token of every request instead
+	// of using Token, e.g. AppAuth.Token for a GitHub App installation.
+	TokenSource func(ctx context.Context) (string, error)
+	BaseURL     string
+	HTTPClient  *http.Client
+	// UserAgent is sent with every request; empty means DefaultUserAgent.
+	UserAgent string
+	// Accept lists extra media types, such as API previews, sent in the
+	// Accept header of every REST request after the default one.
+	Accept []string
+	// CacheDir, when set, caches every search page on disk there. Pages
+	// older than CacheTTL are fetched again; 0 keeps them forever. Cached
+	// pages are served regardless of the token, so don't share a cache
+	// between tokens that can see different repos.
+	CacheDir string
+	CacheTTL time.Duration
+	// Window restricts the search to a date range; the zero value doesn't
+	// restrict it at all.
+	Window DateRange
+
+	// CountOnly reads the counts from the search totals with a single
+	// request per query, without listing PRs. The counts are the same as
+	// those of a full fetch, which also takes them from the totals.
+	CountOnly bool
+	// Limit caps the number of PRs collected per handle, 0 for no limit.
+	// Counts still reflect every matching PR.
+	Limit int
+	// PageSize is the number of PRs requested per search page, up to and by
+	// default 100.
+	PageSize int
+	// CodeownersTeam, when set, only counts PRs touching paths the given
+	// CODEOWNERS owner (e.g. "@org/team-x") owns.
+	CodeownersTeam string
+	// PathFilter, when set, only counts PRs changing a file matched by one
+	// of these gitignore-style patterns, e.g. "services/api/". It costs a
+	// request per PR to list its files.
+	PathFilter []string
+	// TitleMatch, when set, only keeps PRs whose title matches it in the PR
+	// lists. With MatchAffectsCounts the counts only include them as well,
+	// which needs every PR of a query to be fetched.
+	TitleMatch         *regexp.Regexp
+	MatchAffectsCounts bool
+	ExcludeDrafts      bool
+	OnlyDrafts         bool
+	// QueryExtra is appended to every search query.
+	QueryExtra string
+	// IncludeIssues also counts the issues each handle opened, kept apart
+	// from the PR counts in Summary.Issues.
+	IncludeIssues bool
+	// IncludeReviewComments also counts the PRs of others each handle
+	// commented on, kept apart from the PR counts in Summary.ReviewComments.
+	IncludeReviewComments bool
+	// IncludeCommits also counts the commits each handle authored, through
+	// the commit search, in Summary.Commits. The GraphQL API can't search
+	// commits, so they aren't counted with UseGraphQL.
+	IncludeCommits bool
+	// IncludeCoAuthored also counts the merged PRs of others with a commit
+	// crediting each handle in a Co-authored-by trailer, in
+	// Summary.CoAuthored. It lists every merged PR in the orgs or repos
+	// searched and the commits of each, so a handle without any fails.
+	IncludeCoAuthored bool
+	// SkipInactiveRepos leaves out the repository scopes whose last push and
+	// update both predate the window, looking each repository up once
+	// instead of searching it. Org scopes are always searched.
+	SkipInactiveRepos bool
+	// UseGraphQL counts PRs through the GraphQL API, batching many searches
+	// per request. Like CountOnly it only reports counts. When the GraphQL
+	// request fails, Fetch falls back to the REST search API.
+	UseGraphQL bool
+	// Forks, when set to ExcludeForks or OnlyForks, leaves out the PRs in
+	// forked repositories or keeps only those. Like CodeownersTeam it needs
+	// every PR, and its counts come from the PRs kept.
+	Forks string
+	// Logger receives progress messages; nil disables them.
+	Logger *log.Logger
+	// OnPR, when set, is called with every PR added to a summary as soon as
+	// its query completes, before Fetch returns. Handles are fetched
+	// concurrently, so it may be called from several goroutines at once.
+	OnPR func(handle string, pr PullRequest)
+	// OnProgress, when set, is called by Fetch each time a handle is done,
+	// with the number of handles done so far and the total. Calls are
+	// serialized.
+	OnProgress func(done, total int)
+	// OnTokenExpiry, when set, is called once, with the first response
+	// carrying the token's expiration date.
+	OnTokenExpiry func(expires time.Time)
+
+	shared *clientState
+}
+
+// clientState holds the caches and rate-limit bookkeeping shared by a Client
+// and the copies made by WithWindow.
+type clientState struct {
+	mu          sync.Mutex
+	rateLimit   *RateLimit
+	tokenExpiry bool
+	// nextToken is where pickToken resumes in Client.Tokens, and
+	// tokenBudgets is keyed by token and rate-limit resource.
+	nextToken    int
+	tokenBudgets map[string]tokenBudget
+
+	codeownersMu sync.Mutex
+	codeowners   map[string][]codeownersRule
+
+	prFilesMu sync.Mutex
+	prFiles   map[string][]string
+
+	avatarMu sync.Mutex
+	avatars  map[string]avatarLookup
+
+	teamsMu sync.Mutex
+	teams   map[string][]Handle
+
+	forksMu sync.Mutex
+	forks   map[string]bool
+
+	coAuthorsMu sync.Mutex
+	coAuthors   map[string][]coAuthor
+
+	repoActivityMu sync.Mutex
+	repoActivity   map[string]time.Time
+}
+
+// NewClient returns a Client for the public GitHub API.
+func NewClient(token string) *Client {
+	return &Client{
+		Token:      token,
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: http.DefaultClient,
+		UserAgent:  DefaultUserAgent(),
+		shared:     newClientState(),
+	}
+}
+
+func newClientState() *clientState {
+	return &clientState{
+		codeowners: make(map[string][]codeownersRule),
+		prFiles:    make(map[string][]string),
+		avatars:    make(map[string]avatarLookup),
+		teams:      make(map[string][]Handle),
+		forks:      make(map[string]bool),
+		coAuthors:  make(map[string][]coAuthor),
+
+		repoActivity: make(map[string]time.Time),
+
+		tokenBudgets: make(map[string]tokenBudget),
+	}
+}
+
+// state returns the shared state, creating it for Clients that weren't made
+// by NewClient.
+func (c *Client) state() *clientState {
+	if c.shared == nil {
+		c.shared = newClientState()
+	}
+	return c.shared
+}
+
+// WithWindow returns a copy of c searching window instead. The copy shares
+// c's caches and rate-limit bookkeeping.
+func (c *Client) WithWindow(window DateRange) *Client {
+	c.state()
+	copied := *c
+	copied.Window = window
+	return &copied
+}
+
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, args...)
+	}
+}
+
+type PullRequest struct {
+	URL string `json:"url"`
+	// HTMLURL is the PR's page on GitHub, where URL is its API resource.
+	HTMLURL string `json:"html_url,omitempty"`
+	Title   string `json:"title"`
+	Number  int    `json:"number"`
+	// Repository is the "owner/name" of the PR's repository.
+	Repository string `json:"repository"`
+	// State is "open" or "closed"; merged PRs are closed with Merged set.
+	State     string     `json:"state"`
+	Merged    bool       `json:"merged"`
+	CreatedAt time.Time  `json:"created_at"`
+	MergedAt  *time.Time `json:"merged_at,omitempty"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	// Labels are the names of the PR's labels.
+	Labels []string `json:"labels,omitempty"`
+}
+
+// UnmarshalJSON decodes a search API item, where the merge date is nested
+// under pull_request and the repository is only given as an API URL.
+func (pr *PullRequest) UnmarshalJSON(data []byte) error {
+	type rawPullRequest PullRequest
+	var raw struct {
+		rawPullRequest
+		RepositoryURL string `json:"repository_url"`
+		PullRequest   struct {
+			MergedAt *time.Time `json:"merged_at"`
+		} `json:"pull_request"`
+		// Search items carry label objects, PullRequest's own encoding names
+		Labels []json.RawMessage `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*pr = PullRequest(raw.rawPullRequest)
+	for _, data := range raw.Labels {
+		var label struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(data, &label.Name); err != nil {
+			if err := json.Unmarshal(data, &label); err != nil {
+				return err
+			}
+		}
+		pr.Labels = append(pr.Labels, label.Name)
+	}
+	if pr.MergedAt == nil {
+		pr.MergedAt = raw.PullRequest.MergedAt
+	}
+	pr.Merged = pr.Merged || pr.MergedAt != nil
+	if pr.Repository == "" {
+		pr.Repository = repoFromURL(raw.RepositoryURL)
+	}
+	if pr.Repository == "" || pr.Number == 0 {
+		if repo, number, err := parsePRURL(pr.URL); err == nil {
+			pr.Repository, pr.Number = repo, number
+		}
+	}
+	return nil
+}
+
+// key identifies pr across searches: its API URL, or its repository and
+// number when the URL is missing. It is empty when pr has neither, and such
+// PRs are never treated as duplicates.
+func (pr PullRequest) key() string {
+	if pr.URL != "" {
+		return pr.URL
+	}
+	if pr.Number == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s#%d", pr.Repository, pr.Number)
+}
+
+// dedupePRs drops the repeats of PRs listed earlier in prs, keeping the first
+// copy of each in order.
+func dedupePRs(prs []PullRequest) []PullRequest {
+	seen := make(map[string]bool)
+	var unique []PullRequest
+	for _, pr := range prs {
+		if key := pr.key(); key == "" || !seen[key] {
+			seen[key] = true
+			unique = append(unique, pr)
+		}
+	}
+	return unique
+}
+
+// repoFromURL extracts "owner/name" from a repository API URL such as
+// https://api.github.com/repos/owner/name, or returns "".
+func repoFromURL(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 || parts[len(parts)-3] != "repos" {
+		return ""
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
+
+type Summary struct {
+	Handle string
+	Name   string
+	Counts map[string]int
+	PRs    []PullRequest
+	// Issues is the number of issues opened, counted with IncludeIssues.
+	Issues int
+	// ReviewComments is the number of other authors' PRs commented on,
+	// counted with IncludeReviewComments.
+	ReviewComments int
+	// Commits is the number of commits authored, counted with
+	// IncludeCommits.
+	Commits int
+	// CoAuthored is the number of others' merged PRs crediting the handle
+	// as a co-author, counted with IncludeCoAuthored.
+	CoAuthored int
+	// Truncated is set when PRs holds fewer PRs than were counted, because
+	// of Limit or the search API's 1000 result cap.
+	Truncated bool
+	// FirstPR and LastPR are the earliest and latest creation dates in PRs,
+	// zero when there are none.
+	FirstPR time.Time
+	LastPR  time.Time
+	// Repos lists the distinct repositories, e.g. "octo/api", of PRs, sorted.
+	Repos []string
+	// Incomplete is set when GitHub kept reporting incomplete results for
+	// one of the handle's searches, so its counts may be too low.
+	Incomplete bool
+	// Capped is set when a count had to be taken from the PRs a search
+	// listed while it matched more than the 1000 it lists at most, so the
+	// count may be too low.
+	Capped bool
+	// SkippedScopes lists the orgs and repos, e.g. "repo octo/gone", that
+	// couldn't be searched because they don't exist or aren't accessible.
+	SkippedScopes []string
+	// Partials lists the count each search added, in the order they ran.
+	Partials []Partial
+	// Weekly optionally holds totals per week, oldest first. Fetch leaves it
+	// empty; the command fills it in for --sparkline.
+	Weekly []int
+}
+
+// Partial is what one search added to a summary: What is the status, "issues"
+// or "review comments", Scope describes the org or repo searched, e.g.
+// "org octo", and is empty for an unscoped search.
+type Partial struct {
+	What  string
+	Scope string
+	Query string
+	Count int
+}
+
+// Label is the name shown for the summary's row, falling back to the handle.
+func (s Summary) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Handle
+}
+
+// RunResult is the outcome of fetching every configured handle: the summaries
+// of the handles that succeeded, in config order, and the error of each
+// handle that failed. Warnings describe problems that didn't fail the run.
+type RunResult struct {
+	Summaries []Summary
+	Failures  map[string]error
+	Warnings  []string
+	// Unfinished lists, in config order, the handles that were still being
+	// fetched when the context was canceled. They are in neither Summaries
+	// nor Failures.
+	Unfinished []string
+	// SkippedScopes combines the SkippedScopes of all summaries.
+	SkippedScopes []string
+}
+
+// FailedHandles returns the handles that failed, sorted.
+func (r RunResult) FailedHandles() []string {
+	handles := make([]string, 0, len(r.Failures))
+	for handle := range r.Failures {
+		handles = append(handles, handle)
+	}
+	sort.Strings(handles)
+	return handles
+}
+
+// Fetch fetches every handle of config concurrently. Handles that fail are
+// reported in the result's Failures; the error is only set when ctx ends
+// before the fetching does. A status listed twice is only fetched once.
+func (c *Client) Fetch(ctx context.Context, config Config) (RunResult, error) {
+	c.state()
+	config.Statuses = mergeLists(config.Statuses, nil)
+
+	var warnings []string
+	if c.UseGraphQL {
+		result, err := c.fetchGraphQL(ctx, config)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			// The batches fail as a whole, so no handle got its counts
+			result = RunResult{Failures: make(map[string]error)}
+			for _, handle := range config.Handles {
+				result.Unfinished = append(result.Unfinished, handle.Handle)
+			}
+			return result, ctx.Err()
+		}
+		warnings = append(warnings, fmt.Sprintf("GraphQL request failed, falling back to the REST search API: %v", err))
+	}
+
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+	results := make([]Summary, len(config.Handles))
+	errs := make([]error, len(config.Handles))
+
+	for i, handle := range config.Handles {
+		wg.Add(1)
+		go func(i int, handle Handle) {
+			defer wg.Done()
+			results[i], errs[i] = c.fetchPRs(ctx, handle.Handle, config.forHandle(handle))
+			results[i].Name = handle.Name
+			if c.OnProgress != nil {
+				progressMu.Lock()
+				done++
+				c.OnProgress(done, len(config.Handles))
+				progressMu.Unlock()
+			}
+		}(i, handle)
+	}
+
+	wg.Wait()
+
+	result := RunResult{Failures: make(map[string]error), Warnings: warnings}
+	for i, handle := range config.Handles {
+		if errs[i] != nil && ctx.Err() != nil && errors.Is(errs[i], ctx.Err()) {
+			result.Unfinished = append(result.Unfinished, handle.Handle)
+			continue
+		}
+		if errs[i] != nil {
+			result.Failures[handle.Handle] = errs[i]
+			continue
+		}
+		result.Summaries = append(result.Summaries, results[i])
+		if results[i].Incomplete {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("GitHub returned incomplete search results for %s even after retrying, so its counts may be too low", handle.Handle))
+		}
+		for _, scope := range results[i].SkippedScopes {
+			if !contains(result.SkippedScopes, scope) {
+				result.SkippedScopes = append(result.SkippedScopes, scope)
+				result.Warnings = append(result.Warnings, fmt.Sprintf("skipped %s, which doesn't exist or can't be searched with this token", scope))
+			}
+		}
+	}
+	return result, ctx.Err()
+}
+
+func (c *Client) fetchPRs(ctx context.Context, handle string, config Config) (Summary, error) {
+	summary := Summary{
+		Handle: handle,
+		Counts: make(map[string]int),
+	}
+
+	scopes := c.activeScopes(ctx, searchScopes(config.Orgs, config.Repos, config.Scopes))
+	for _, status := range config.Statuses {
+		query := fmt.Sprintf("author:%s is:pr", handle) + statusQualifiers(status, config.Statuses)
+
+		query += c.Window.FieldQualifiers(config.DateField(status))
+		query += c.searchFilters()
+		query += authorExclusions(config.ExcludeAuthors)
+
+		skipped := 0
+		for _, scope := range scopes {
+			before := summary.Counts[status]
+			err := c.fetchQuery(ctx, &summary, status, query+scope.qualifier, scope.description)
+			if scope.qualifier != "" && isUnsearchable(err) {
+				c.logf("Skipping%s for %s: %v\n", scope.description, handle, err)
+				summary.SkippedScopes = appendMissing(summary.SkippedScopes, strings.TrimPrefix(scope.description, " in "))
+				skipped++
+				// When no scope can be searched, the handle is the likely culprit
+				if skipped == len(scopes) {
+					return summary, err
+				}
+				continue
+			}
+			if err != nil {
+				return summary, err
+			}
+			summary.addPartial(status, scope, query, summary.Counts[status]-before)
+		}
+	}
+
+	if c.IncludeIssues {
+		query := fmt.Sprintf("author:%s is:issue", handle) + c.Window.Qualifiers("") + c.issueFilters() + authorExclusions(config.ExcludeAuthors)
+		for _, scope := range scopes {
+			count, err := c.countSearch(ctx, &summary, issuesSearch, "issues", query+scope.qualifier, scope.description)
+			if err != nil {
+				return summary, err
+			}
+			summary.Issues += count
+			summary.addPartial("issues", scope, query, count)
+		}
+	}
+
+	if c.IncludeReviewComments {
+		query := c.reviewCommentsQuery(handle, config)
+		for _, scope := range scopes {
+			count, err := c.countSearch(ctx, &summary, issuesSearch, "commented PRs", query+scope.qualifier, scope.description)
+			if err != nil {
+				return summary, err
+			}
+			summary.ReviewComments += count
+			summary.addPartial("review comments", scope, query, count)
+		}
+	}
+
+	if c.IncludeCommits {
+		query := c.commitsQuery(handle)
+		for _, scope := range scopes {
+			count, err := c.countSearch(ctx, &summary, commitsSearch, "commits", query+scope.qualifier, scope.description)
+			if err != nil {
+				return summary, err
+			}
+			summary.Commits += count
+			summary.addPartial("commits", scope, query, count)
+		}
+	}
+
+	if c.IncludeCoAuthored {
+		query := c.coAuthoredQuery(handle, config)
+		for _, scope := range scopes {
+			count, err := c.countCoAuthored(ctx, &summary, query, scope)
+			if err != nil {
+				return summary, err
+			}
+			summary.CoAuthored += count
+			summary.addPartial("co-authored PRs", scope, query, count)
+		}
+	}
+
+	// A safety net for PRs matched by overlapping scopes or repeated pages
+	summary.PRs = dedupePRs(summary.PRs)
+	summary.FirstPR, summary.LastPR = prDateRange(summary.PRs)
+	summary.Repos = uniqueRepos(summary.PRs)
+	return summary, nil
+}
+
+// addPartial records count as added by the search of query in scope.
+func (s *Summary) addPartial(what string, scope searchScope, query string, count int) {
+	s.Partials = append(s.Partials, Partial{What: what, Scope: strings.TrimPrefix(scope.description, " in "), Query: query + scope.qualifier, Count: count})
+}
+
+// reviewCommentsQuery searches the PRs handle commented on, leaving out its
+// own PRs, which the PR counts already cover.
+func (c *Client) reviewCommentsQuery(handle string, config Config) string {
+	return fmt.Sprintf("commenter:%s is:pr -author:%s", handle, handle) + c.Window.Qualifiers("") + c.searchFilters() + authorExclusions(config.ExcludeAuthors)
+}
+
+// The search endpoints countSearch can query.
+const (
+	issuesSearch  = "/search/issues"
+	commitsSearch = "/search/commits"
+)
+
+// commitsQuery searches the commits handle authored in the window. The PR
+// filters don't apply to commits.
+func (c *Client) commitsQuery(handle string) string {
+	return fmt.Sprintf("author:%s", handle) + c.Window.FieldQualifiers("author-date")
+}
+
+// countSearch returns the number of items, described as what in logs,
+// matching query on the endpoint search for summary's handle. Only the
+// search total is needed, so a single one-item page is requested.
+func (c *Client) countSearch(ctx context.Context, summary *Summary, endpoint, what, query, scope string) (int, error) {
+	path := endpoint + "?q=" + url.QueryEscape(query)
+	c.logf("Fetching %s for %s%s with query: %s\n", what, summary.Handle, scope, c.BaseURL+path)
+
+	result, err := c.makeRequest(ctx, path+"&per_page=1")
+	if err != nil {
+		return 0, err
+	}
+	summary.Incomplete = summary.Incomplete || result.IncompleteResults
+	return result.TotalCount, nil
+}
+
+// isUnsearchable reports whether err says a scope's org or repo doesn't
+// exist or can't be accessed. The search API answers those with a 422
+// "cannot be searched" rather than a 404.
+func isUnsearchable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	text := strings.ToLower(apiErr.Message + " " + strings.Join(apiErr.Details, " "))
+	return apiErr.StatusCode == http.StatusUnprocessableEntity && strings.Contains(text, "cannot be searched")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMissing appends v to list unless it is already there.
+func appendMissing(list []string, v string) []string {
+	if contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
+
+// searchScope restricts a query to an org or some repos.
+type searchScope struct {
+	qualifier   string
+	description string
+	// org is the org of an org scope and repo the "owner/name" of a single
+	// repository scope, "" otherwise.
+	org  string
+	repo string
+}
+
+// searchScopes turns the configured orgs, repos and nested scopes into the
+// list of scopes to query. Flat orgs take precedence over flat repos, nested
+// scopes are always added, and with nothing configured a single unscoped
+// query is made. Scopes covered by another one are left out, see
+// disjointScopes.
+func searchScopes(orgs []string, repos []string, scopes []Scope) []searchScope {
+	var result []searchScope
+	if len(orgs) > 0 {
+		for _, org := range orgs {
+			result = append(result, searchScope{qualifier: fmt.Sprintf(" org:%s", org), description: " in org " + org, org: org})
+		}
+	} else if len(repos) > 0 {
+		for _, repo := range repos {
+			result = append(result, searchScope{qualifier: fmt.Sprintf(" repo:%s", repo), description: " in repo " + repo, repo: repo})
+		}
+	}
+
+	for _, scope := range scopes {
+		if len(scope.Repos) == 0 {
+			result = append(result, searchScope{qualifier: fmt.Sprintf(" org:%s", scope.Org), description: " in org " + scope.Org, org: scope.Org})
+			continue
+		}
+		for _, repo := range scope.Repos {
+			if !strings.Contains(repo, "/") {
+				repo = scope.Org + "/" + repo
+			}
+			result = append(result, searchScope{qualifier: fmt.Sprintf(" org:%s repo:%s", scope.Org, repo), description: " in repo " + repo, repo: repo})
+		}
+	}
+
+	if len(result) == 0 {
+		result = append(result, searchScope{})
+	}
+	return disjointScopes(result)
+}
+
+// disjointScopes drops the scopes whose PRs another scope already matches: a
+// repeated org or repo, and repos of an org that is searched as a whole,
+// e.g. a flat orgs entry and a nested scope for the same org. Every PR is
+// then found by one scope only and counted once. GitHub names are compared
+// ignoring case.
+func disjointScopes(scopes []searchScope) []searchScope {
+	orgs := make(map[string]bool)
+	for _, scope := range scopes {
+		if scope.org != "" {
+			orgs[strings.ToLower(scope.org)] = true
+		}
+	}
+	seen := make(map[string]bool)
+	var disjoint []searchScope
+	for _, scope := range scopes {
+		key := strings.ToLower(scope.org)
+		if scope.repo != "" {
+			owner, _, _ := strings.Cut(scope.repo, "/")
+			if orgs[strings.ToLower(owner)] {
+				continue
+			}
+			key = strings.ToLower(scope.repo)
+		}
+		if seen[key] && key != "" {
+			continue
+		}
+		seen[key] = true
+		disjoint = append(disjoint, scope)
+	}
+	return disjoint
+}
+
+// prDateRange returns the earliest and latest creation dates of prs.
+func prDateRange(prs []PullRequest) (time.Time, time.Time) {
+	var first, last time.Time
+	for _, pr := range prs {
+		if first.IsZero() || pr.CreatedAt.Before(first) {
+			first = pr.CreatedAt
+		}
+		if pr.CreatedAt.After(last) {
+			last = pr.CreatedAt
+		}
+	}
+	return first, last
+}
+
+// statusQualifiers returns the qualifiers selecting status. GitHub counts
+// merged PRs as closed, so when merged is queried too, closed leaves them out
+// with is:unmerged and the two columns don't overlap.
+func statusQualifiers(status string, statuses []string) string {
+	if status == "closed" && contains(statuses, "merged") {
+		return " is:closed is:unmerged"
+	}
+	return " is:" + status
+}
+
+// uniqueRepos returns the sorted, distinct repositories of prs, skipping PRs
+// whose repository couldn't be parsed.
+func uniqueRepos(prs []PullRequest) []string {
+	var repos []string
+	for _, pr := range prs {
+		if pr.Repository != "" && !contains(repos, pr.Repository) {
+			repos = append(repos, pr.Repository)
+		}
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// searchFilters returns the qualifiers added to every query by the filtering
+// options.
+func (c *Client) searchFilters() string {
+	var q string
+	if c.ExcludeDrafts {
+		q += " draft:false"
+	}
+	if c.OnlyDrafts {
+		q += " draft:true"
+	}
+	if extra := strings.TrimSpace(c.QueryExtra); extra != "" {
+		q += " " + extra
+	}
+	return q
+}
+
+// authorExclusions returns a -author: qualifier per excluded author.
+func authorExclusions(authors []string) string {
+	var q string
+	for _, author := range authors {
+		q += " -author:" + author
+	}
+	return q
+}
+
+// issueFilters is searchFilters without the draft qualifiers, which only
+// apply to PRs.
+func (c *Client) issueFilters() string {
+	if extra := strings.TrimSpace(c.QueryExtra); extra != "" {
+		return " " + extra
+	}
+	return ""
+}
+
+// fetchQuery runs a single search query and adds its results to summary under
+// status. scope only describes the query in logs.
+func (c *Client) fetchQuery(ctx context.Context, summary *Summary, status, query, scope string) error {
+	path := "/search/issues?q=" + url.QueryEscape(query)
+
+	c.logf("Fetching %s PRs for %s%s with query: %s\n", status, summary.Handle, scope, c.BaseURL+path)
+
+	// Counts only need total_count, so a single one-item page is enough
+	if c.CountOnly {
+		result, err := c.makeRequest(ctx, path+"&per_page=1")
+		if err != nil {
+			return err
+		}
+		summary.Counts[status] += result.TotalCount
+		summary.Incomplete = summary.Incomplete || result.IncompleteResults
+		return nil
+	}
+
+	// Counting matched PRs needs all of them, so Limit only caps the list then
+	countsFromItems := c.CountsNeedPRs()
+	remaining := 0
+	if c.Limit > 0 {
+		remaining = max(c.Limit-len(summary.PRs), 0)
+		if remaining == 0 && !countsFromItems {
+			result, err := c.makeRequest(ctx, path+"&per_page=1")
+			if err != nil {
+				return err
+			}
+			summary.Counts[status] += result.TotalCount
+			summary.Truncated = summary.Truncated || result.TotalCount > 0
+			summary.Incomplete = summary.Incomplete || result.IncompleteResults
+			return nil
+		}
+	}
+
+	maxItems := remaining
+	if countsFromItems {
+		maxItems = 0
+	}
+	result, err := c.searchPages(ctx, path, maxItems)
+	if err != nil {
+		return err
+	}
+	summary.Incomplete = summary.Incomplete || result.IncompleteResults
+
+	prs, err := c.filterByFork(ctx, result.Items)
+	if err != nil {
+		return err
+	}
+	if prs, err = c.filterByCodeowners(ctx, prs); err != nil {
+		return err
+	}
+	if prs, err = c.filterByPaths(ctx, prs); err != nil {
+		return err
+	}
+	matched := c.filterByTitle(prs)
+	if c.MatchAffectsCounts {
+		prs = matched
+	}
+	if countsFromItems {
+		summary.Counts[status] += len(prs)
+		summary.Capped = summary.Capped || result.TotalCount > searchResultCap
+	} else {
+		summary.Counts[status] += result.TotalCount
+	}
+	prs = matched
+	if c.Limit > 0 && len(prs) > remaining {
+		prs = prs[:remaining]
+		summary.Truncated = true
+	}
+	if !countsFromItems && len(result.Items) < result.TotalCount {
+		summary.Truncated = true
+	}
+	summary.PRs = append(summary.PRs, prs...)
+	if c.OnPR != nil {
+		for _, pr := range prs {
+			c.OnPR(summary.Handle, pr)
+		}
+	}
+	return nil
+}
+
+// CountsNeedPRs reports whether the counts are taken from the PRs themselves
+// rather than the search totals, because a filter the search can't express,
+// such as CodeownersTeam, Forks or PathFilter, drops some of them. CountOnly
+// counts would then be too high.
+func (c *Client) CountsNeedPRs() bool {
+	return c.CodeownersTeam != "" || c.Forks != "" || len(c.PathFilter) > 0 || (c.TitleMatch != nil && c.MatchAffectsCounts)
+}
+
+// filterByTitle keeps the PRs whose title matches TitleMatch, or all of them
+// when it is unset.
+func (c *Client) filterByTitle(prs []PullRequest) []PullRequest {
+	if c.TitleMatch == nil {
+		return prs
+	}
+	var matched []PullRequest
+	for _, pr := range prs {
+		if c.TitleMatch.MatchString(pr.Title) {
+			matched = append(matched, pr)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,502 @@
+package pullpanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// searchServer answers every issue search with the PRs of items, whatever the
+// query, and records the queries it was sent.
+func searchServer(t *testing.T, items string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/search/issues") {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		queries = append(queries, r.URL.Query().Get("q"))
+		mu.Unlock()
+		count := strings.Count(items, `"url"`)
+		fmt.Fprintf(w, `{"total_count":%d,"items":[%s]}`, count, items)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), queries...)
+	}
+}
+
+func testClient(baseURL string) *Client {
+	client := NewClient("test-token")
+	client.BaseURL = baseURL
+	return client
+}
+
+func TestFetchCountsOverlappingScopesOnce(t *testing.T) {
+	srv, queries := searchServer(t, `{"url":"https://api.github.com/repos/octo/api/issues/1","number":1,"repository_url":"https://api.github.com/repos/octo/api"}`)
+	client := testClient(srv.URL)
+	var streamed []string
+	client.OnPR = func(handle string, pr PullRequest) { streamed = append(streamed, pr.key()) }
+
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Orgs:     []string{"octo"},
+		Scopes:   []Scope{{Org: "Octo"}, {Org: "octo", Repos: []string{"api"}}},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(result.Summaries))
+	}
+	summary := result.Summaries[0]
+	if got := summary.Counts["merged"]; got != 1 {
+		t.Errorf("merged count = %d, want 1", got)
+	}
+	if len(summary.PRs) != 1 || len(streamed) != 1 {
+		t.Errorf("got %d PRs and %d streamed, want 1 each", len(summary.PRs), len(streamed))
+	}
+	if got := queries(); len(got) != 1 {
+		t.Errorf("sent %d searches, want 1: %q", len(got), got)
+	}
+}
+
+func TestSearchScopesDropsCoveredScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		orgs   []string
+		repos  []string
+		scopes []Scope
+		want   []string
+	}{
+		{"unscoped", nil, nil, nil, []string{""}},
+		{"repeated org", []string{"octo", "octo"}, nil, nil, []string{" org:octo"}},
+		{"flat and nested org", []string{"octo"}, nil, []Scope{{Org: "OCTO"}}, []string{" org:octo"}},
+		{"repo of a searched org", nil, nil, []Scope{{Org: "octo"}, {Org: "octo", Repos: []string{"api", "web"}}}, []string{" org:octo"}},
+		{"repo of a flat repos org", nil, []string{"octo/api"}, []Scope{{Org: "octo"}}, []string{" org:octo"}},
+		{"repeated repo", nil, []string{"octo/api", "Octo/API"}, nil, []string{" repo:octo/api"}},
+		{"disjoint", []string{"octo"}, nil, []Scope{{Org: "hub", Repos: []string{"cli"}}}, []string{" org:octo", " org:hub repo:hub/cli"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, scope := range searchScopes(tt.orgs, tt.repos, tt.scopes) {
+				got = append(got, scope.qualifier)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchScopes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupePRsKeepsFirstCopy(t *testing.T) {
+	prs := []PullRequest{
+		{URL: "a", Title: "first"},
+		{URL: "b"},
+		{URL: "a", Title: "second"},
+		{Title: "no key"},
+		{Title: "no key"},
+	}
+	got := dedupePRs(prs)
+	if len(got) != 4 || got[0].Title != "first" {
+		t.Errorf("dedupePRs = %+v, want the first copy of a and both keyless PRs", got)
+	}
+}
+
+func TestDraftFiltersQualifyQueries(t *testing.T) {
+	tests := []struct {
+		exclude, only bool
+		want, notWant string
+	}{
+		{exclude: true, want: " draft:false", notWant: "draft:true"},
+		{only: true, want: " draft:true", notWant: "draft:false"},
+		{notWant: "draft:"},
+	}
+	for _, tt := range tests {
+		srv, queries := searchServer(t, "")
+		client := testClient(srv.URL)
+		client.ExcludeDrafts = tt.exclude
+		client.OnlyDrafts = tt.only
+		if _, err := client.Fetch(context.Background(), Config{
+			Handles:  []Handle{{Handle: "octocat"}},
+			Statuses: []string{"open", "merged"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range queries() {
+			if !strings.Contains(q, tt.want) || strings.Contains(q, tt.notWant) {
+				t.Errorf("exclude=%v only=%v: query %q, want %q and no %q", tt.exclude, tt.only, q, tt.want, tt.notWant)
+			}
+		}
+	}
+}
+
+// TestQueryExtraIsEncodedAndAppended checks that --query-extra ends each
+// query, escaped in the request URL like the rest of the query.
+func TestQueryExtraIsEncodedAndAppended(t *testing.T) {
+	var mu sync.Mutex
+	var rawQueries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		rawQueries = append(rawQueries, r.URL.RawQuery)
+		mu.Unlock()
+		fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.QueryExtra = `  label:"good first issue" language:go `
+	if _, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Statuses: []string{"merged"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rawQueries) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(rawQueries))
+	}
+	values, err := url.ParseQuery(rawQueries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := values.Get("q"); !strings.HasSuffix(q, ` label:"good first issue" language:go`) {
+		t.Errorf("query %q doesn't end with the extra qualifiers", q)
+	}
+	if strings.ContainsAny(rawQueries[0], ` "`) {
+		t.Errorf("raw query %q isn't escaped", rawQueries[0])
+	}
+}
+
+// TestIncludeIssuesCountsIssuesApart answers the PR and issue searches with
+// different totals and checks each lands in its own count.
+func TestIncludeIssuesCountsIssuesApart(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		if strings.Contains(q, "is:issue") {
+			fmt.Fprint(w, `{"total_count":5,"items":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":2,"items":[{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"repository_url":"https://api.github.com/repos/o/r"},{"url":"https://api.github.com/repos/o/r/issues/2","number":2,"repository_url":"https://api.github.com/repos/o/r"}]}`)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.IncludeIssues = true
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prQueries, issueQueries int
+	for _, q := range queries {
+		switch {
+		case strings.Contains(q, "is:pr") && !strings.Contains(q, "is:issue"):
+			prQueries++
+		case strings.Contains(q, "is:issue") && !strings.Contains(q, "is:pr"):
+			issueQueries++
+		default:
+			t.Errorf("query %q mixes or lacks is:pr and is:issue", q)
+		}
+	}
+	if prQueries != 1 || issueQueries != 1 {
+		t.Errorf("sent %d PR and %d issue queries, want 1 of each", prQueries, issueQueries)
+	}
+	summary := result.Summaries[0]
+	if summary.Counts["merged"] != 2 || summary.Issues != 5 {
+		t.Errorf("merged = %d, issues = %d, want 2 and 5", summary.Counts["merged"], summary.Issues)
+	}
+}
+
+func TestTitleMatchFiltersPRs(t *testing.T) {
+	srv, _ := searchServer(t, `{"url":"https://api.github.com/repos/o/r/issues/1","number":1,"title":"fix: login","repository_url":"https://api.github.com/repos/o/r"},`+
+		`{"url":"https://api.github.com/repos/o/r/issues/2","number":2,"title":"docs: Fix typo","repository_url":"https://api.github.com/repos/o/r"},`+
+		`{"url":"https://api.github.com/repos/o/r/issues/3","number":3,"title":"feat: sso","repository_url":"https://api.github.com/repos/o/r"}`)
+	tests := []struct {
+		pattern       string
+		affectsCounts bool
+		wantPRs       []int
+		wantCount     int
+	}{
+		{`^fix`, false, []int{1}, 3},
+		{`(?i)fix`, true, []int{1, 2}, 2},
+		{`^chore`, true, nil, 0},
+	}
+	for _, tt := range tests {
+		client := testClient(srv.URL)
+		client.TitleMatch = regexp.MustCompile(tt.pattern)
+		client.MatchAffectsCounts = tt.affectsCounts
+		result, err := client.Fetch(context.Background(), Config{Handles: []Handle{{Handle: "octocat"}}, Statuses: []string{"merged"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary := result.Summaries[0]
+		var numbers []int
+		for _, pr := range summary.PRs {
+			numbers = append(numbers, pr.Number)
+		}
+		if !reflect.DeepEqual(numbers, tt.wantPRs) || summary.Counts["merged"] != tt.wantCount {
+			t.Errorf("%q with affects-counts %v: PRs %v counted %d, want %v counted %d", tt.pattern, tt.affectsCounts, numbers, summary.Counts["merged"], tt.wantPRs, tt.wantCount)
+		}
+	}
+}
+
+// TestMissingScopeIsSkipped answers the searches of one repo with a 404 and
+// checks the other repo is still counted, and that a handle is only failed
+// when none of its scopes can be searched.
+func TestMissingScopeIsSkipped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "repo:octo/missing") {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":1,"items":[{"url":"https://api.github.com/repos/octo/api/issues/1","number":1,"repository_url":"https://api.github.com/repos/octo/api"}]}`)
+	}))
+	defer srv.Close()
+	client := testClient(srv.URL)
+
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/api", "octo/missing"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failures) != 0 || len(result.Summaries) != 1 || result.Summaries[0].Counts["merged"] != 1 {
+		t.Fatalf("result = %+v, want octocat counted 1 in octo/api", result)
+	}
+	if len(result.SkippedScopes) != 1 || !strings.Contains(result.SkippedScopes[0], "octo/missing") {
+		t.Errorf("skipped scopes = %q, want octo/missing", result.SkippedScopes)
+	}
+
+	result, err = client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/missing"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, failed := result.Failures["octocat"]; !failed {
+		t.Errorf("failures = %v, want octocat failed with its only scope missing", result.Failures)
+	}
+}
+
+func TestExcludeAuthorsQualifyQueries(t *testing.T) {
+	srv, queries := searchServer(t, "")
+	if _, err := testClient(srv.URL).Fetch(context.Background(), Config{
+		Handles:        []Handle{{Handle: "octocat"}},
+		Statuses:       []string{"merged", "open"},
+		ExcludeAuthors: []string{"app/dependabot", "octo-ci"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range queries() {
+		if !strings.HasSuffix(q, " -author:app/dependabot -author:octo-ci") {
+			t.Errorf("query %q doesn't exclude the authors", q)
+		}
+	}
+}
+
+// searchItem is an issue search result for a merged PR, trimmed from a real
+// response.
+const searchItem = `{
+  "url": "https://api.github.com/repos/octo/api/issues/1347",
+  "repository_url": "https://api.github.com/repos/octo/api",
+  "html_url": "https://github.com/octo/api/pull/1347",
+  "id": 1,
+  "number": 1347,
+  "title": "Fix the login redirect",
+  "user": {"login": "octocat", "id": 1},
+  "labels": [{"id": 208045946, "name": "bug", "color": "f29513"}],
+  "state": "closed",
+  "comments": 2,
+  "created_at": "2024-01-10T09:00:00Z",
+  "updated_at": "2024-01-12T10:00:00Z",
+  "closed_at": "2024-01-12T10:00:00Z",
+  "draft": false,
+  "pull_request": {
+    "url": "https://api.github.com/repos/octo/api/pulls/1347",
+    "html_url": "https://github.com/octo/api/pull/1347",
+    "merged_at": "2024-01-12T10:00:00Z"
+  },
+  "score": 1.0
+}`
+
+func TestDecodeSearchItem(t *testing.T) {
+	var pr PullRequest
+	if err := json.Unmarshal([]byte(searchItem), &pr); err != nil {
+		t.Fatal(err)
+	}
+	merged := time.Date(2024, 1, 12, 10, 0, 0, 0, time.UTC)
+	want := PullRequest{
+		URL:        "https://api.github.com/repos/octo/api/issues/1347",
+		HTMLURL:    "https://github.com/octo/api/pull/1347",
+		Title:      "Fix the login redirect",
+		Number:     1347,
+		Repository: "octo/api",
+		State:      "closed",
+		Merged:     true,
+		CreatedAt:  time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+		MergedAt:   &merged,
+		ClosedAt:   &merged,
+		Labels:     []string{"bug"},
+	}
+	if !reflect.DeepEqual(pr, want) {
+		t.Errorf("decoded %+v, want %+v", pr, want)
+	}
+
+	// PRs encoded by pullpanda itself, e.g. for --output jsonl, decode the same
+	encoded, err := json.Marshal(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again PullRequest
+	if err := json.Unmarshal(encoded, &again); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, want) {
+		t.Errorf("round trip gave %+v, want %+v", again, want)
+	}
+
+	// Without repository_url, the repository and number come from the URL
+	var bare PullRequest
+	if err := json.Unmarshal([]byte(`{"url":"https://api.github.com/repos/hub/cli/issues/9"}`), &bare); err != nil {
+		t.Fatal(err)
+	}
+	if bare.Repository != "hub/cli" || bare.Number != 9 {
+		t.Errorf("decoded repository %q and number %d, want hub/cli and 9", bare.Repository, bare.Number)
+	}
+}
+
+func TestUniqueRepos(t *testing.T) {
+	prs := []PullRequest{
+		{Repository: "octo/web", Number: 1},
+		{Repository: "octo/api", Number: 2},
+		{Repository: "hub/cli", Number: 3},
+		{Repository: "octo/web", Number: 4},
+		{Number: 5},
+	}
+	if got, want := uniqueRepos(prs), []string{"hub/cli", "octo/api", "octo/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueRepos = %q, want %q", got, want)
+	}
+	if got := uniqueRepos(nil); got != nil {
+		t.Errorf("uniqueRepos(nil) = %q, want none", got)
+	}
+}
+
+func TestIncludeReviewCommentsQueriesCommenter(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		total := 0
+		if strings.HasPrefix(q, "commenter:") {
+			total = 4
+		}
+		fmt.Fprintf(w, `{"total_count":%d,"items":[]}`, total)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.IncludeReviewComments = true
+	client.Window = DateRange{Start: "2024-01-01"}
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/api"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "commenter:octocat is:pr -author:octocat created:>=2024-01-01 repo:octo/api"
+	if !slices.Contains(queries, want) {
+		t.Errorf("queries = %q, want %q among them", queries, want)
+	}
+	if got := result.Summaries[0]; got.ReviewComments != 4 || got.Counts["merged"] != 0 {
+		t.Errorf("review comments = %d, merged = %d, want 4 kept apart from the PR counts", got.ReviewComments, got.Counts["merged"])
+	}
+}
+
+func TestStatusQualifiers(t *testing.T) {
+	tests := []struct {
+		status   string
+		statuses []string
+		want     string
+	}{
+		{"closed", []string{"closed", "merged"}, " is:closed is:unmerged"},
+		{"closed", []string{"closed"}, " is:closed"},
+		{"merged", []string{"closed", "merged"}, " is:merged"},
+		{"open", []string{"open", "merged"}, " is:open"},
+	}
+	for _, tt := range tests {
+		if got := statusQualifiers(tt.status, tt.statuses); got != tt.want {
+			t.Errorf("statusQualifiers(%q, %q) = %q, want %q", tt.status, tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeCommitsCountsCommitSearch(t *testing.T) {
+	var mu sync.Mutex
+	var commitQueries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/commits" {
+			fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+			return
+		}
+		if accept := r.Header.Get("Accept"); !strings.HasPrefix(accept, "application/vnd.github.cloak-preview+json") {
+			t.Errorf("commit search Accept = %q, want the cloak preview", accept)
+		}
+		mu.Lock()
+		commitQueries = append(commitQueries, r.URL.Query().Get("q"))
+		mu.Unlock()
+		fmt.Fprint(w, `{"total_count":42,"items":[{"sha":"abc123"}]}`)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL).WithWindow(DateRange{Start: "2024-01-01", End: "2024-01-31"})
+	client.IncludeCommits = true
+	result, err := client.Fetch(context.Background(), Config{
+		Handles:  []Handle{{Handle: "octocat"}},
+		Repos:    []string{"octo/api"},
+		Statuses: []string{"merged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"author:octocat author-date:>=2024-01-01 author-date:<=2024-01-31 repo:octo/api"}; !slices.Equal(commitQueries, want) {
+		t.Errorf("commit queries = %q, want %q", commitQueries, want)
+	}
+	if got := result.Summaries[0].Commits; got != 42 {
+		t.Errorf("Commits = %d, want the total_count 42", got)
+	}
+}
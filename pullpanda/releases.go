@@ -0,0 +1,34 @@
+package pullpanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Release is a published release of a repository.
+type Release struct {
+	Tag         string    `json:"tag_name"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// LatestRelease returns the latest published release of repo, given as
+// "owner/name". Drafts and prereleases don't count, as for GitHub's "Latest"
+// badge.
+func (c *Client) LatestRelease(ctx context.Context, repo string) (Release, error) {
+	var release Release
+	err := c.fetchJSON(ctx, "/repos/"+repo+"/releases/latest", &release)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return release, fmt.Errorf("%s has no published release, or doesn't exist or isn't accessible with this token", repo)
+	}
+	if err != nil {
+		return release, fmt.Errorf("error looking up the latest release of %s: %w", repo, err)
+	}
+	if release.PublishedAt.IsZero() {
+		return release, fmt.Errorf("the latest release of %s has no publication date", repo)
+	}
+	return release, nil
+}
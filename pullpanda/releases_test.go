@@ -0,0 +1,43 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatestRelease(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/api/releases/latest":
+			fmt.Fprint(w, `{"tag_name":"v1.4.0","name":"1.4","draft":false,"prerelease":false,"published_at":"2024-01-15T12:30:00Z"}`)
+		case "/repos/octo/unpublished/releases/latest":
+			fmt.Fprint(w, `{"tag_name":"v0.1.0","published_at":null}`)
+		default:
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := testClient(srv.URL)
+
+	release, err := client.LatestRelease(context.Background(), "octo/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Release{Tag: "v1.4.0", PublishedAt: time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC)}); release != want {
+		t.Errorf("LatestRelease = %+v, want %+v", release, want)
+	}
+
+	for repo, want := range map[string]string{
+		"octo/empty":       "octo/empty has no published release",
+		"octo/unpublished": "the latest release of octo/unpublished has no publication date",
+	} {
+		if _, err := client.LatestRelease(context.Background(), repo); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", repo, err, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// TeamMembers returns the members of a team given as "org/slug", looked up
+// once per Client through the teams API. The token needs the read:org scope.
+// The cache isn't locked while fetching, so lookups of other teams don't
+// wait on it.
+func (c *Client) TeamMembers(ctx context.Context, team string) ([]Handle, error) {
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok || org == "" || slug == "" {
+		return nil, fmt.Errorf("invalid team %q, expected org/slug", team)
+	}
+
+	s := c.state()
+	s.teamsMu.Lock()
+	cached, ok := s.teams[team]
+	s.teamsMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var members []Handle
+	for page := 1; ; page++ {
+		var result []struct {
+			Login string `json:"login"`
+		}
+		membersPath := fmt.Sprintf("/orgs/%s/teams/%s/members?per_page=100&page=%d", url.PathEscape(org), url.PathEscape(slug), page)
+		if err := c.fetchJSON(ctx, membersPath, &result); err != nil {
+			return nil, fmt.Errorf("error listing members of team %s (the token needs the read:org scope): %w", team, err)
+		}
+		for _, member := range result {
+			members = append(members, Handle{Handle: member.Login})
+		}
+		if len(result) < 100 {
+			break
+		}
+	}
+	c.logf("Team %s has %d members\n", team, len(members))
+
+	s.teamsMu.Lock()
+	s.teams[team] = members
+	s.teamsMu.Unlock()
+	return members, nil
+}
@@ -0,0 +1,96 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestTeamMembersPagesAndCaches lists a team of 150 members over two pages
+// of the team-members endpoint, then looks it up again from the cache.
+func TestTeamMembersPagesAndCaches(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/orgs/octo/teams/api-team/members" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var members []string
+		for i := (page - 1) * 100; i < min(page*100, 150); i++ {
+			members = append(members, fmt.Sprintf(`{"login":"user%d"}`, i))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(members, ","))
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	members, err := client.TeamMembers(context.Background(), "octo/api-team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 150 || members[0].Handle != "user0" || members[149].Handle != "user149" {
+		t.Errorf("got %d members, %v..., want user0 to user149", len(members), members[:min(len(members), 2)])
+	}
+	again, err := client.TeamMembers(context.Background(), "octo/api-team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, members) {
+		t.Error("cached lookup returned other members")
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("sent %d requests, want the 2 pages once", got)
+	}
+
+	if _, err := client.TeamMembers(context.Background(), "octo/missing"); err == nil || !strings.Contains(err.Error(), "read:org") {
+		t.Errorf("missing team error = %v, want a hint at the read:org scope", err)
+	}
+	if _, err := client.TeamMembers(context.Background(), "octo"); err == nil {
+		t.Error("accepted a team without a slug")
+	}
+}
+
+// TestTeamMembersLookupsRunConcurrently holds the response for one team
+// until the other has been listed, which deadlocks if the lookups are
+// serialized.
+func TestTeamMembersLookupsRunConcurrently(t *testing.T) {
+	fastDone := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/slow/") {
+			select {
+			case <-fastDone:
+			case <-time.After(5 * time.Second):
+				t.Error("the slow team's lookup blocked the fast one")
+			}
+		}
+		fmt.Fprint(w, `[{"login":"octocat"}]`)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := client.TeamMembers(context.Background(), "octo/slow"); err != nil {
+			t.Error(err)
+		}
+	}()
+	// Give the slow lookup time to start first
+	time.Sleep(20 * time.Millisecond)
+	if _, err := client.TeamMembers(context.Background(), "octo/fast"); err != nil {
+		t.Error(err)
+	}
+	close(fastDone)
+	wg.Wait()
+}
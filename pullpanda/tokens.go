@@ -0,0 +1,111 @@
+package pullpanda
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenBudget is the rate limit last reported for one token and resource.
+type tokenBudget struct {
+	remaining int
+	reset     time.Time
+}
+
+// resourceOf names the rate-limit bucket a request to path draws from, as
+// GitHub reports it in X-RateLimit-Resource.
+func resourceOf(path string) string {
+	if strings.HasPrefix(path, "/search/") {
+		return "search"
+	}
+	return "core"
+}
+
+// exhausted reports whether b says the budget is used up until a reset that
+// hasn't happened yet.
+func (b tokenBudget) exhausted() bool {
+	return b.remaining <= 0 && time.Now().Before(b.reset)
+}
+
+// pickToken returns the next of Tokens, in turn, with budget left for
+// resource. Tokens never seen in a response count as having budget. When
+// every token is used up, the one resetting first is returned.
+func (c *Client) pickToken(resource string) string {
+	s := c.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	soonest := -1
+	for i := 0; i < len(c.Tokens); i++ {
+		n := (s.nextToken + i) % len(c.Tokens)
+		budget, seen := s.tokenBudgets[c.Tokens[n]+"\x00"+resource]
+		if !seen || !budget.exhausted() {
+			s.nextToken = n + 1
+			return c.Tokens[n]
+		}
+		if soonest < 0 || budget.reset.Before(s.tokenBudgets[c.Tokens[soonest]+"\x00"+resource].reset) {
+			soonest = n
+		}
+	}
+	return c.Tokens[soonest]
+}
+
+// hasSpareToken reports whether another of Tokens than token still has
+// budget for resource.
+func (c *Client) hasSpareToken(token, resource string) bool {
+	s := c.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range c.Tokens {
+		if budget, seen := s.tokenBudgets[t+"\x00"+resource]; t != token && (!seen || !budget.exhausted()) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordTokenBudget keeps the rate limit h reports for the token req was
+// sent with, so pickToken can skip it once it's used up.
+func (c *Client) recordTokenBudget(req *http.Request, h http.Header) {
+	if len(c.Tokens) == 0 {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, _ := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	resource := h.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = resourceOf(c.apiPath(req.URL))
+	}
+
+	s := c.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenBudgets[requestToken(req)+"\x00"+resource] = tokenBudget{remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// apiPath returns the path of u below BaseURL, so that on GitHub Enterprise
+// Server "/api/v3/search/issues" is classified like "/search/issues".
+func (c *Client) apiPath(u *url.URL) string {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return u.Path
+	}
+	return strings.TrimPrefix(u.Path, strings.TrimSuffix(base.Path, "/"))
+}
+
+// requestToken returns the token in req's Authorization header.
+func requestToken(req *http.Request) string {
+	_, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	return token
+}
+
+// isRateLimited reports whether resp was refused because the token's rate
+// limit is used up.
+func isRateLimited(resp *http.Response) bool {
+	return (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) && resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
@@ -0,0 +1,97 @@
+package pullpanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestTokensRotateWhenExhausted serves searches with two tokens, the first of
+// which has its search rate limit used up, and checks the client moves on to
+// the second and keeps using it.
+func TestTokensRotateWhenExhausted(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	var used []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := requestToken(r)
+		used = append(used, token)
+		w.Header().Set("X-RateLimit-Resource", "search")
+		w.Header().Set("X-RateLimit-Reset", reset)
+		if token == "first" {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			http.Error(w, `{"message":"API rate limit exceeded"}`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "29")
+		fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	client.Tokens = []string{"first", "second"}
+	for i := 0; i < 3; i++ {
+		query := fmt.Sprintf("/search/issues?q=author%%3Aoctocat&page=%d", i)
+		if _, err := client.searchOnce(context.Background(), query); err != nil {
+			t.Fatalf("search %d failed: %v", i, err)
+		}
+	}
+
+	want := []string{"first", "second", "second", "second"}
+	if !reflect.DeepEqual(used, want) {
+		t.Errorf("tokens used = %q, want %q", used, want)
+	}
+	if client.hasSpareToken("second", "search") {
+		t.Error("hasSpareToken reports the exhausted first token as spare")
+	}
+}
+
+// TestPickTokenFallsBackToSoonestReset checks that once every token is used
+// up, the one resetting first is picked.
+func TestPickTokenFallsBackToSoonestReset(t *testing.T) {
+	client := testClient("")
+	client.Tokens = []string{"late", "soon"}
+	s := client.state()
+	s.tokenBudgets["late\x00search"] = tokenBudget{reset: time.Now().Add(time.Hour)}
+	s.tokenBudgets["soon\x00search"] = tokenBudget{reset: time.Now().Add(time.Minute)}
+
+	if got := client.pickToken("search"); got != "soon" {
+		t.Errorf("pickToken = %q, want %q", got, "soon")
+	}
+	if got := client.pickToken("core"); got != "late" {
+		t.Errorf("pickToken for another resource = %q, want %q", got, "late")
+	}
+}
+
+// TestRecordTokenBudgetBelowEnterprisePrefix checks that a search sent
+// through a GitHub Enterprise Server base URL, whose paths start with
+// /api/v3, is recorded against the search budget when the response doesn't
+// name its resource.
+func TestRecordTokenBudgetBelowEnterprisePrefix(t *testing.T) {
+	tests := []struct {
+		baseURL, url, want string
+	}{
+		{"https://api.github.com", "https://api.github.com/search/issues", "search"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/search/issues", "search"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/v3/search/commits", "search"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/repos/octo/api", "core"},
+	}
+	for _, tt := range tests {
+		client := testClient(tt.baseURL)
+		client.Tokens = []string{"first"}
+		req := httptest.NewRequest("GET", tt.url, nil)
+		req.Header.Set("Authorization", "token first")
+		h := http.Header{}
+		h.Set("X-RateLimit-Remaining", "0")
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		client.recordTokenBudget(req, h)
+
+		if _, ok := client.state().tokenBudgets["first\x00"+tt.want]; !ok {
+			t.Errorf("%s: budget not recorded for %q, got %v", tt.url, tt.want, client.state().tokenBudgets)
+		}
+	}
+}